@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// topicAssertion — проверка из --assert: topic=partitions:N,
+// topic=min-replication:N или topic=messages>0.
+type topicAssertion struct {
+	Topic string
+	Check string
+	Value int64
+}
+
+func (a topicAssertion) String() string {
+	if a.Check == "messages" {
+		return a.Topic + "=messages>0"
+	}
+	return fmt.Sprintf("%s=%s:%d", a.Topic, a.Check, a.Value)
+}
+
+// parseAssertions разбирает список проверок через запятую.
+func parseAssertions(s string) ([]topicAssertion, error) {
+	var assertions []topicAssertion
+	for _, item := range splitList(s) {
+		topic, check, ok := strings.Cut(item, "=")
+		topic = strings.TrimSpace(topic)
+		check = strings.TrimSpace(check)
+		if !ok || topic == "" {
+			return nil, fmt.Errorf("bad item %q, want topic=partitions:N, topic=min-replication:N or topic=messages>0", item)
+		}
+		if check == "messages>0" {
+			assertions = append(assertions, topicAssertion{Topic: topic, Check: "messages"})
+			continue
+		}
+		kind, value, ok := strings.Cut(check, ":")
+		if !ok || (kind != "partitions" && kind != "min-replication") {
+			return nil, fmt.Errorf("bad check %q in %q, use partitions:N, min-replication:N or messages>0", check, item)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad number in %q", item)
+		}
+		assertions = append(assertions, topicAssertion{Topic: topic, Check: kind, Value: n})
+	}
+	return assertions, nil
+}
+
+// needsMinReplicas сообщает, есть ли среди проверок min-replication.
+func needsMinReplicas(assertions []topicAssertion) bool {
+	for _, a := range assertions {
+		if a.Check == "min-replication" {
+			return true
+		}
+	}
+	return false
+}
+
+// failedAssertions проверяет строки топиков и возвращает описания нарушений.
+// Топик, которого нет в отчёте, — тоже нарушение.
+func failedAssertions(assertions []topicAssertion, rows map[string]Row) []string {
+	var failed []string
+	for _, a := range assertions {
+		r, ok := rows[a.Topic]
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s: topic is not in the report", a))
+			continue
+		}
+		switch a.Check {
+		case "partitions":
+			if int64(r.Partitions) != a.Value {
+				failed = append(failed, fmt.Sprintf("%s: got %d partitions", a, r.Partitions))
+			}
+		case "min-replication":
+			if int64(r.MinReplicas) < a.Value {
+				failed = append(failed, fmt.Sprintf("%s: got replication %d", a, r.MinReplicas))
+			}
+		case "messages":
+			if r.Messages <= 0 {
+				failed = append(failed, fmt.Sprintf("%s: got %d messages", a, r.Messages))
+			}
+		}
+	}
+	return failed
+}
+
+// checkAssertions завершает работу с ошибкой, если какая-то проверка не прошла.
+func checkAssertions(assertions []topicAssertion, rows map[string]Row) {
+	failed := failedAssertions(assertions, rows)
+	for _, f := range failed {
+		log.Printf("ASSERT FAILED: %s", f)
+	}
+	if len(failed) > 0 {
+		fatalf("%d of %d assertions failed", len(failed), len(assertions))
+	}
+}
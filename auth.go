@@ -0,0 +1,235 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// saslCredentials — логин и пароль для механизмов PLAIN и SCRAM.
+type saslCredentials struct {
+	Username string
+	Password string
+}
+
+type gssapiOptions struct {
+	ServiceName string
+	Realm       string
+	Username    string
+	Password    string
+	KeytabPath  string
+	ConfigPath  string
+	KDC         string
+}
+
+// securityProtocols — значения --security-protocol, как security.protocol
+// в конфигурации клиентов Kafka.
+var securityProtocols = []string{"PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL"}
+
+// configureSecurityProtocol включает TLS по протоколу и проверяет, что
+// SASL-механизм задан ровно для SASL_* протоколов. Пустой протокол
+// выводится из механизма: PLAINTEXT или SASL_PLAINTEXT.
+func configureSecurityProtocol(cfg *sarama.Config, protocol, mechanism string) error {
+	protocol = strings.ToUpper(protocol)
+	if protocol == "" {
+		return nil
+	}
+	if !slices.Contains(securityProtocols, protocol) {
+		return fmt.Errorf("unsupported protocol %q, use one of: %s", protocol, strings.Join(securityProtocols, ", "))
+	}
+	sasl := strings.HasPrefix(protocol, "SASL_")
+	if sasl && mechanism == "" {
+		return fmt.Errorf("%s requires --sasl-mechanism", protocol)
+	}
+	if !sasl && mechanism != "" {
+		return fmt.Errorf("%s does not use SASL, but --sasl-mechanism %s is set", protocol, mechanism)
+	}
+	if strings.HasSuffix(protocol, "SSL") {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return nil
+}
+
+// configureSASL включает SASL-аутентификацию по выбранному механизму.
+// Возвращаемую функцию нужно вызвать по завершении работы: она удаляет
+// временные файлы, созданные для аутентификации.
+func configureSASL(cfg *sarama.Config, mechanism string, creds saslCredentials, gss gssapiOptions, awsRegion string) (func(), error) {
+	noop := func() {}
+
+	switch m := strings.ToUpper(mechanism); m {
+	case "":
+		return noop, nil
+	case sarama.SASLTypeGSSAPI:
+		return configureGSSAPI(cfg, gss)
+	case sarama.SASLTypePlaintext:
+		return noop, configurePlain(cfg, creds)
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		return noop, configureSCRAM(cfg, m, creds)
+	case saslTypeMSKIAM:
+		return noop, configureMSKIAM(cfg, awsRegion)
+	default:
+		return noop, fmt.Errorf("unsupported mechanism %q, use one of: GSSAPI, PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, AWS_MSK_IAM", mechanism)
+	}
+}
+
+// configurePlain включает SASL/PLAIN: пароль передаётся открытым текстом,
+// поэтому механизм имеет смысл только поверх TLS (SASL_SSL).
+func configurePlain(cfg *sarama.Config, creds saslCredentials) error {
+	if err := checkCredentials(sarama.SASLTypePlaintext, creds); err != nil {
+		return err
+	}
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	cfg.Net.SASL.User = creds.Username
+	cfg.Net.SASL.Password = creds.Password
+	return nil
+}
+
+func configureSCRAM(cfg *sarama.Config, mechanism string, creds saslCredentials) error {
+	if err := checkCredentials(mechanism, creds); err != nil {
+		return err
+	}
+
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = sarama.SASLMechanism(mechanism)
+	cfg.Net.SASL.User = creds.Username
+	cfg.Net.SASL.Password = creds.Password
+	cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+		return &scramClient{HashGeneratorFcn: scramHash(mechanism)}
+	}
+	return nil
+}
+
+func checkCredentials(mechanism string, creds saslCredentials) error {
+	var missing []string
+	if creds.Username == "" {
+		missing = append(missing, "--sasl-username")
+	}
+	if creds.Password == "" {
+		missing = append(missing, "--sasl-password")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s requires %s", mechanism, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// scramHash выбирает хеш-функцию SCRAM по имени механизма: брокер отвергает
+// клиента, который считает подпись не той функцией, общей ошибкой
+// аутентификации.
+func scramHash(mechanism string) scram.HashGeneratorFcn {
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		return sha512.New
+	}
+	return sha256.New
+}
+
+// scramClient реализует sarama.SCRAMClient поверх github.com/xdg-go/scram.
+type scramClient struct {
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
+
+func configureGSSAPI(cfg *sarama.Config, gss gssapiOptions) (func(), error) {
+	cleanup := func() {}
+
+	var missing []string
+	if gss.ServiceName == "" {
+		missing = append(missing, "--kerberos-service-name")
+	}
+	if gss.Realm == "" {
+		missing = append(missing, "--kerberos-realm")
+	}
+	if gss.Username == "" {
+		missing = append(missing, "--kerberos-username")
+	}
+	if gss.KeytabPath == "" && gss.Password == "" {
+		missing = append(missing, "--kerberos-keytab or --kerberos-password")
+	}
+	if gss.ConfigPath == "" && gss.KDC == "" {
+		missing = append(missing, "--kerberos-config or --kerberos-kdc")
+	}
+	if len(missing) > 0 {
+		return cleanup, fmt.Errorf("GSSAPI requires %s", strings.Join(missing, ", "))
+	}
+	if gss.KeytabPath != "" {
+		if _, err := os.Stat(gss.KeytabPath); err != nil {
+			return cleanup, fmt.Errorf("keytab: %w", err)
+		}
+	}
+
+	krb5Path := gss.ConfigPath
+	if krb5Path == "" {
+		// krb5.conf не задан — собираем минимальный конфиг из realm и адреса KDC
+		path, err := writeKrb5Config(gss.Realm, gss.KDC)
+		if err != nil {
+			return cleanup, err
+		}
+		krb5Path = path
+		cleanup = func() { os.Remove(path) }
+	}
+
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Mechanism = sarama.SASLTypeGSSAPI
+	cfg.Net.SASL.GSSAPI.ServiceName = gss.ServiceName
+	cfg.Net.SASL.GSSAPI.Realm = gss.Realm
+	cfg.Net.SASL.GSSAPI.Username = gss.Username
+	cfg.Net.SASL.GSSAPI.KerberosConfigPath = krb5Path
+	if gss.KeytabPath != "" {
+		cfg.Net.SASL.GSSAPI.AuthType = sarama.KRB5_KEYTAB_AUTH
+		cfg.Net.SASL.GSSAPI.KeyTabPath = gss.KeytabPath
+	} else {
+		cfg.Net.SASL.GSSAPI.AuthType = sarama.KRB5_USER_AUTH
+		cfg.Net.SASL.GSSAPI.Password = gss.Password
+	}
+
+	return cleanup, nil
+}
+
+func writeKrb5Config(realm, kdc string) (string, error) {
+	f, err := os.CreateTemp("", "kafka-topics-report-krb5-*.conf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create krb5.conf: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "[libdefaults]\n"+
+		"  default_realm = %s\n"+
+		"  dns_lookup_kdc = false\n"+
+		"  dns_lookup_realm = false\n"+
+		"\n"+
+		"[realms]\n"+
+		"  %s = {\n"+
+		"    kdc = %s\n"+
+		"  }\n", realm, realm, kdc)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write krb5.conf: %w", err)
+	}
+	return f.Name(), nil
+}
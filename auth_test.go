@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+func TestSCRAMClientFirstMessage(t *testing.T) {
+	for _, mechanism := range []string{sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512} {
+		t.Run(mechanism, func(t *testing.T) {
+			cfg := sarama.NewConfig()
+			if err := configureSCRAM(cfg, mechanism, saslCredentials{Username: "alice", Password: "secret"}); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Net.SASL.Mechanism != sarama.SASLMechanism(mechanism) {
+				t.Errorf("mechanism = %s, want %s", cfg.Net.SASL.Mechanism, mechanism)
+			}
+			client := cfg.Net.SASL.SCRAMClientGeneratorFunc()
+			if err := client.Begin("alice", "secret", ""); err != nil {
+				t.Fatal(err)
+			}
+			first, err := client.Step("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// gs2-заголовок без channel binding и authzid, имя и nonce клиента
+			if !strings.HasPrefix(first, "n,,n=alice,r=") || len(first) == len("n,,n=alice,r=") {
+				t.Errorf("client-first message = %q, want n,,n=alice,r=<nonce>", first)
+			}
+			if client.Done() {
+				t.Error("conversation done after client-first message")
+			}
+		})
+	}
+}
+
+// TestSCRAMExchange проводит полный обмен с сервером SCRAM той же хеш-функции
+// и проверяет длину доказательства клиента: 32 байта у SHA-256, 64 у SHA-512.
+func TestSCRAMExchange(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		server    scram.HashGeneratorFcn
+		proofLen  int
+	}{
+		{sarama.SASLTypeSCRAMSHA256, scram.SHA256, sha256.Size},
+		{sarama.SASLTypeSCRAMSHA512, scram.SHA512, sha512.Size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mechanism, func(t *testing.T) {
+			stored, err := tt.server.NewClient("alice", "secret", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			creds := stored.GetStoredCredentials(scram.KeyFactors{Salt: "pepper", Iters: 4096})
+			server, err := tt.server.NewServer(func(string) (scram.StoredCredentials, error) { return creds, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			conv := server.NewConversation()
+
+			client := &scramClient{HashGeneratorFcn: scramHash(tt.mechanism)}
+			if err := client.Begin("alice", "secret", ""); err != nil {
+				t.Fatal(err)
+			}
+			var proof string
+			msg, err := client.Step("")
+			for err == nil && !client.Done() {
+				if msg, err = conv.Step(msg); err != nil {
+					t.Fatalf("server step: %v", err)
+				}
+				if msg, err = client.Step(msg); err == nil && strings.Contains(msg, ",p=") {
+					proof = msg[strings.LastIndex(msg, ",p=")+3:]
+				}
+			}
+			if err != nil {
+				t.Fatalf("client step: %v", err)
+			}
+			if !conv.Valid() {
+				t.Fatal("server rejected the client proof")
+			}
+			raw, err := base64.StdEncoding.DecodeString(proof)
+			if err != nil || len(raw) != tt.proofLen {
+				t.Errorf("client proof %q: %d bytes (%v), want %d", proof, len(raw), err, tt.proofLen)
+			}
+		})
+	}
+}
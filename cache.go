@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// cacheKey — параметры, от которых зависят собранные строки. Кеш, собранный
+// с другими параметрами, не используется.
+type cacheKey struct {
+	Brokers           []string
+	BusinessRegexp    string
+	InternalPrefix    string
+	Group             string
+	TopicGrep         string
+	Topics            []string
+	TopicPatterns     []string
+	MinPartitions     int
+	MaxPartitions     int
+	ConsumersFilter   string
+	OverPartitioned   float64
+	OnlyNeverWritten  bool
+	Columns           []string
+	AutoDetailSkew    int64
+	RateDetail        bool
+	ReplicaAssignment bool
+	MessageWindow     time.Duration
+	ExcludeControl    bool
+	CountMode         string
+	AtTimestamp       string
+	RateInterval      time.Duration
+
+	ExpectedPartitions map[string]int32
+	Categories         topicCategories
+}
+
+// topicsCache — снимок строк отчёта по топикам (--cache-file).
+type topicsCache struct {
+	CreatedAt time.Time
+	Key       cacheKey
+	Rows      []Row
+}
+
+// loadCache читает строки из кеша. ok == false, если кеша нет, он старше
+// maxAge (0 — без ограничения) или собран с другими параметрами.
+func loadCache(path string, key cacheKey, maxAge time.Duration) (rows []Row, createdAt time.Time, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	var c topicsCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if maxAge > 0 && time.Since(c.CreatedAt) > maxAge {
+		return nil, c.CreatedAt, false, nil
+	}
+	if !reflect.DeepEqual(c.Key, key) {
+		return nil, c.CreatedAt, false, nil
+	}
+	return c.Rows, c.CreatedAt, true, nil
+}
+
+// saveCache записывает строки во временный файл рядом с path и переименовывает
+// его, чтобы прерванный запуск не оставил обрезанный кеш.
+func saveCache(path string, key cacheKey, rows []Row) error {
+	data, err := json.Marshal(topicsCache{CreatedAt: time.Now(), Key: key, Rows: rows})
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Row — строка отчёта по одному топику. Строка с Detail == true описывает
+// одну партицию топика (--auto-detail-skew).
+type Row struct {
+	Topic      string
+	Detail     bool
+	Partition  int32
+	Partitions int32
+	Consumers  int64
+	Messages   int64
+
+	// GroupCount — число групп, читающих топик, в отличие от Consumers —
+	// суммы их участников (--group-count)
+	GroupCount int32
+
+	// ExpectedPartitions — ожидаемое число партиций из --expected-partitions,
+	// 0 если для топика ничего не задано.
+	ExpectedPartitions int32
+
+	// MsgsPerSec — скорость записи в топик по двум снимкам (--rate-interval)
+	MsgsPerSec float64
+
+	// Skew — разница между самой большой и самой маленькой партицией
+	Skew int64
+
+	// ActivePartitions — партиции топика, в которых есть сообщения
+	// (--active-partitions)
+	ActivePartitions int32
+
+	// SkewStddev и SkewCV — стандартное отклонение и коэффициент вариации
+	// числа сообщений по партициям (--skew-metric)
+	SkewStddev float64
+	SkewCV     float64
+
+	// P95PartitionMessages — 95-й перцентиль числа сообщений по партициям
+	// (--with-percentiles)
+	P95PartitionMessages int64
+
+	// MinReplicas — наименьшее число реплик среди партиций, 0 если неизвестно
+	MinReplicas int32
+
+	// MinISR — действующее значение min.insync.replicas топика, 0 если
+	// неизвестно (--min-isr)
+	MinISR int32
+
+	// LastCommitAge — давность последнего коммита самой отстающей из читающих
+	// групп (--commit-age), отрицательное значение — неизвестно
+	LastCommitAge time.Duration
+
+	// OldestMessageAge — давность самой старой записи топика
+	// (--oldest-message-age), отрицательное значение — неизвестно
+	OldestMessageAge time.Duration
+
+	// OffsetAtTime — сумма оффсетов партиций на момент --at-timestamp,
+	// отрицательное значение — неизвестно
+	OffsetAtTime int64
+
+	// Category — business, changelog, repartition или internal (--category)
+	Category string
+
+	// GroupLag — отставание группы из --group по топику
+	GroupLag int64
+
+	// TotalLag — суммарное отставание всех читающих групп (--total-lag)
+	TotalLag int64
+
+	// GroupsNoCommit — группы с активными консьюмерами, но без коммитов
+	// по топику (--groups-no-commit)
+	GroupsNoCommit []string
+
+	// Replicas и ISR — брокеры партиции в порядке назначения (первый —
+	// предпочтительный лидер), только для строк партиций (--replica-assignment)
+	Replicas []int32
+	ISR      []int32
+
+	// NonPreferredLeaders — партиции топика, лидер которых не первая реплика;
+	// LeaderNotPreferred — то же для строки партиции, nil — неизвестно
+	// (--preferred-leader)
+	NonPreferredLeaders int32
+	LeaderNotPreferred  *bool
+
+	// LeaderBrokers — адреса брокеров-лидеров партиций топика (--leader-brokers)
+	LeaderBrokers []string
+
+	// ReplicaLag — наибольшее отставание реплик партиции в сообщениях,
+	// отрицательное значение — неизвестно (--replica-lag)
+	ReplicaLag int64
+
+	// ReplicationLag — наибольшее отставание реплик среди партиций топика,
+	// отрицательное значение — неизвестно (--replication-lag)
+	ReplicationLag int64
+
+	// Incomplete — оффсеты топика собраны не полностью (--topic-timeout)
+	Incomplete bool
+
+	// NeverWritten — в топик ни разу не писали, nil — неизвестно
+	// (--never-written)
+	NeverWritten *bool
+
+	// LeaderEpoch — эпоха лидера партиции из метаданных клиента,
+	// отрицательное значение — неизвестно (--leader-epoch)
+	LeaderEpoch int32
+
+	// OffsetsUnknown — оффсеты партиции получить не удалось: строка
+	// партиции выводится ради реплик и ISR, колонки сообщений пустые
+	OffsetsUnknown bool
+}
+
+// column описывает колонку отчёта. Value возвращает nil, если значение
+// для строки отсутствует — такая ячейка выводится пустой.
+type column struct {
+	Name string
+	// Type — тип непустого значения в терминах JSON Schema (--print-schema)
+	Type  string
+	Value func(r Row) any
+}
+
+// topicLevel оборачивает значение, которое имеет смысл только для строки
+// топика: в строках партиций такая ячейка пустая.
+func topicLevel(value func(r Row) any) func(r Row) any {
+	return func(r Row) any {
+		if r.Detail {
+			return nil
+		}
+		return value(r)
+	}
+}
+
+var baseColumns = []column{
+	{"topic", "string", func(r Row) any { return r.Topic }},
+	{"partitions", "integer", topicLevel(func(r Row) any { return r.Partitions })},
+	{"consumers", "integer", topicLevel(func(r Row) any { return r.Consumers })},
+	{"messages", "integer", func(r Row) any {
+		if r.OffsetsUnknown {
+			return nil
+		}
+		return r.Messages
+	}},
+}
+
+var partitionColumn = column{"partition", "integer", func(r Row) any {
+	if !r.Detail {
+		return nil
+	}
+	return r.Partition
+}}
+
+// skewColumns — колонка перекоса партиций по каждой из skewMetrics.
+var skewColumns = map[string]column{
+	"range":  {"skew", "integer", topicLevel(func(r Row) any { return r.Skew })},
+	"stddev": {"skew_stddev", "number", topicLevel(func(r Row) any { return r.SkewStddev })},
+	"cv":     {"skew_cv", "number", topicLevel(func(r Row) any { return r.SkewCV })},
+}
+
+var expectedPartitionsColumns = []column{
+	{"partitions_expected", "integer", topicLevel(func(r Row) any {
+		if r.ExpectedPartitions == 0 {
+			return nil
+		}
+		return r.ExpectedPartitions
+	})},
+	{"matches", "boolean", topicLevel(func(r Row) any {
+		if r.ExpectedPartitions == 0 {
+			return nil
+		}
+		return r.Partitions == r.ExpectedPartitions
+	})},
+}
+
+var singleReplicaColumns = []column{
+	{"single_replica", "boolean", topicLevel(func(r Row) any {
+		if r.MinReplicas == 0 {
+			return nil
+		}
+		return r.MinReplicas == 1
+	})},
+}
+
+var minISRColumns = []column{
+	{"replication_factor", "integer", topicLevel(func(r Row) any {
+		if r.MinReplicas == 0 {
+			return nil
+		}
+		return r.MinReplicas
+	})},
+	{"min_isr", "integer", topicLevel(func(r Row) any {
+		if r.MinISR == 0 {
+			return nil
+		}
+		return r.MinISR
+	})},
+	{"durable", "boolean", topicLevel(func(r Row) any {
+		if r.MinReplicas == 0 || r.MinISR == 0 {
+			return nil
+		}
+		return r.MinISR < r.MinReplicas
+	})},
+}
+
+var commitAgeColumns = []column{
+	{"last_commit_age", "string", topicLevel(func(r Row) any {
+		if r.LastCommitAge < 0 {
+			return nil
+		}
+		return r.LastCommitAge.Round(time.Second).String()
+	})},
+}
+
+var oldestMessageAgeColumns = []column{
+	{"oldest_message_age", "string", topicLevel(func(r Row) any {
+		if r.OldestMessageAge < 0 {
+			return nil
+		}
+		return r.OldestMessageAge.Round(time.Second).String()
+	})},
+}
+
+var offsetAtTimeColumns = []column{
+	{"offset_at_time", "integer", topicLevel(func(r Row) any {
+		if r.OffsetAtTime < 0 {
+			return nil
+		}
+		return r.OffsetAtTime
+	})},
+}
+
+var categoryColumns = []column{
+	{"category", "string", topicLevel(func(r Row) any { return r.Category })},
+}
+
+var groupLagColumns = []column{
+	{"lag", "integer", topicLevel(func(r Row) any { return r.GroupLag })},
+}
+
+var totalLagColumns = []column{
+	{"total_lag", "integer", topicLevel(func(r Row) any { return r.TotalLag })},
+}
+
+var groupCountColumns = []column{
+	{"group_count", "integer", topicLevel(func(r Row) any { return r.GroupCount })},
+}
+
+var groupsNoCommitColumns = []column{
+	{"groups_no_commit", "array", topicLevel(func(r Row) any {
+		if r.GroupsNoCommit == nil {
+			return []string{}
+		}
+		return r.GroupsNoCommit
+	})},
+}
+
+var rateColumns = []column{
+	{"msgs_per_sec", "number", func(r Row) any {
+		if r.OffsetsUnknown {
+			return nil
+		}
+		return r.MsgsPerSec
+	}},
+}
+
+// replicaAssignmentColumns заполнены только в строках партиций.
+var replicaAssignmentColumns = []column{
+	{"replicas", "array", func(r Row) any { return brokerList(r.Replicas) }},
+	{"isr", "array", func(r Row) any { return brokerList(r.ISR) }},
+}
+
+func brokerList(ids []int32) any {
+	if ids == nil {
+		return nil
+	}
+	return ids
+}
+
+var nonPreferredLeadersColumns = []column{
+	{"non_preferred_leaders", "integer", topicLevel(func(r Row) any { return r.NonPreferredLeaders })},
+}
+
+var leaderBrokersColumns = []column{
+	{"leader_brokers", "string", topicLevel(func(r Row) any {
+		if len(r.LeaderBrokers) == 0 {
+			return nil
+		}
+		return strings.Join(r.LeaderBrokers, ";")
+	})},
+}
+
+var preferredLeaderImbalanceColumns = []column{
+	{"preferred_leader_imbalance", "boolean", func(r Row) any {
+		if !r.Detail || r.LeaderNotPreferred == nil {
+			return nil
+		}
+		return *r.LeaderNotPreferred
+	}},
+}
+
+var neverWrittenColumns = []column{
+	{"never_written", "boolean", topicLevel(func(r Row) any {
+		if r.NeverWritten == nil {
+			return nil
+		}
+		return *r.NeverWritten
+	})},
+}
+
+var replicaLagColumns = []column{
+	{"replica_lag", "integer", func(r Row) any {
+		if !r.Detail || r.ReplicaLag < 0 {
+			return nil
+		}
+		return r.ReplicaLag
+	}},
+}
+
+var leaderEpochColumns = []column{
+	{"leader_epoch", "integer", func(r Row) any {
+		if !r.Detail || r.LeaderEpoch < 0 {
+			return nil
+		}
+		return r.LeaderEpoch
+	}},
+}
+
+// overPartitionedColumns — среднее число сообщений на партицию
+// (--over-partitioned-threshold).
+var overPartitionedColumns = []column{
+	{"msgs_per_partition", "number", topicLevel(func(r Row) any {
+		if r.Partitions == 0 {
+			return nil
+		}
+		return float64(r.Messages) / float64(r.Partitions)
+	})},
+}
+
+var percentileColumns = []column{
+	{"p95_partition_messages", "integer", topicLevel(func(r Row) any { return r.P95PartitionMessages })},
+}
+
+var activePartitionsColumns = []column{
+	{"active_partitions", "integer", topicLevel(func(r Row) any { return r.ActivePartitions })},
+	{"avg_msgs_per_active_partition", "number", topicLevel(func(r Row) any {
+		if r.ActivePartitions == 0 {
+			return nil
+		}
+		return float64(r.Messages) / float64(r.ActivePartitions)
+	})},
+}
+
+var replicationLagColumns = []column{
+	{"replication_lag", "integer", topicLevel(func(r Row) any {
+		if r.ReplicationLag < 0 {
+			return nil
+		}
+		return r.ReplicationLag
+	})},
+}
+
+var incompleteColumns = []column{
+	{"incomplete", "boolean", topicLevel(func(r Row) any { return r.Incomplete })},
+}
+
+func columnNames(columns []column) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func rowValues(columns []column, r Row) []any {
+	values := make([]any, len(columns))
+	for i, c := range columns {
+		values[i] = c.Value(r)
+	}
+	return values
+}
+
+// rowSchema описывает объект строки отчёта в JSON Schema. Поле допускает
+// null, если колонка даёт пустое значение для топика без данных или, при
+// detail, для строки партиции.
+func rowSchema(columns []column, detail bool) map[string]any {
+	// пустые значения проверяются на «пустой» строке: нулевые счётчики,
+	// давность коммита неизвестна
+	probes := []Row{{LastCommitAge: -1, OldestMessageAge: -1, OffsetAtTime: -1, ReplicationLag: -1}}
+	if detail {
+		probes = append(probes, Row{Detail: true, LastCommitAge: -1, OldestMessageAge: -1, ReplicaLag: -1, LeaderEpoch: -1})
+	}
+
+	properties := make(map[string]any, len(columns))
+	required := make([]string, len(columns))
+	for i, c := range columns {
+		var typ any = c.Type
+		for _, r := range probes {
+			if c.Value(r) == nil {
+				typ = []string{c.Type, "null"}
+				break
+			}
+		}
+		properties[c.Name] = map[string]any{"type": typ}
+		// jsonObject выводит все поля, пустые — как null
+		required[i] = c.Name
+	}
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "kafka-topics-report row",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func writeRowSchema(w io.Writer, columns []column, detail bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rowSchema(columns, detail))
+}
+
+// writeTopicRows выводит строки отчёта по топикам целиком.
+func writeTopicRows(out rowWriter, columns []column, rows []Row) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := out.WriteRow(rowValues(columns, r)); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+// writeOrderedRows выводит строки, упорядоченные по sortBy (nil — в исходном
+// порядке) и ограниченные первыми limit топиками.
+func writeOrderedRows(out rowWriter, columns []column, rows []Row, sortBy *column, desc bool, limit int) error {
+	if sortBy != nil {
+		rows = sortRows(rows, *sortBy, desc)
+	}
+	return writeTopicRows(out, columns, limitTopics(rows, limit))
+}
+
+// sortRows упорядочивает топики по значению колонки key; строки партиций
+// остаются сразу за строкой своего топика. Пустые значения идут последними
+// при любом направлении, при равенстве сохраняется исходный порядок.
+func sortRows(rows []Row, key column, desc bool) []Row {
+	groups := topicGroups(rows)
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := key.Value(groups[i][0]), key.Value(groups[j][0])
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		if desc {
+			return compareValues(b, a) < 0
+		}
+		return compareValues(a, b) < 0
+	})
+	sorted := make([]Row, 0, len(rows))
+	for _, g := range groups {
+		sorted = append(sorted, g...)
+	}
+	return sorted
+}
+
+// limitTopics оставляет первые n топиков вместе с их строками партиций.
+func limitTopics(rows []Row, n int) []Row {
+	groups := topicGroups(rows)
+	if n <= 0 || n >= len(groups) {
+		return rows
+	}
+	return rows[:len(rows)-countRows(groups[n:])]
+}
+
+// topicGroups делит строки на группы: строка топика и следующие за ней
+// строки партиций.
+func topicGroups(rows []Row) [][]Row {
+	var groups [][]Row
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i == len(rows) || !rows[i].Detail {
+			groups = append(groups, rows[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+func countRows(groups [][]Row) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g)
+	}
+	return n
+}
+
+// compareValues сравнивает значения одной колонки.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case int32:
+		return cmp.Compare(a, b.(int32))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		if !a {
+			return -1
+		}
+		return 1
+	default:
+		return cmp.Compare(formatValue(a), formatValue(b))
+	}
+}
+
+// collapseRows объединяет топики с одинаковыми первыми depth сегментами имени
+// (через точку) в одну строку: партиции, консьюмеры, сообщения и скорость
+// суммируются. Порядок групп — по первому топику группы.
+func collapseRows(rows []Row, depth int) []Row {
+	var collapsed []Row
+	index := make(map[string]int)
+	for _, r := range rows {
+		if r.Detail {
+			continue
+		}
+		key := topicPrefix(r.Topic, depth)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(collapsed)
+			collapsed = append(collapsed, Row{Topic: key})
+			i = len(collapsed) - 1
+		}
+		c := &collapsed[i]
+		c.Partitions += r.Partitions
+		c.Consumers += r.Consumers
+		c.Messages += r.Messages
+		c.MsgsPerSec = roundRate(c.MsgsPerSec + r.MsgsPerSec)
+	}
+	return collapsed
+}
+
+// topicPrefix возвращает первые depth сегментов имени через точку.
+func topicPrefix(name string, depth int) string {
+	segments := strings.SplitN(name, ".", depth+1)
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return strings.Join(segments, ".")
+}
+
+// partitionRows разворачивает топик в строки по всем его партициям в порядке
+// номеров. Партиции, оффсеты которых получить не удалось, тоже получают
+// строку — с пустыми колонками сообщений (OffsetsUnknown).
+func partitionRows(t string, stats topicStats) []Row {
+	rows := make([]Row, 0, stats.Partitions)
+	for p := range stats.Partitions {
+		n, ok := stats.PartitionMessages[p]
+		rows = append(rows, Row{Topic: t, Detail: true, Partition: p, Messages: n, OffsetsUnknown: !ok})
+	}
+	return rows
+}
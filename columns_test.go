@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartitionRowsKeepsFailedPartitions(t *testing.T) {
+	stats := topicStats{
+		Partitions:        3,
+		PartitionMessages: map[int32]int64{0: 10, 2: 0},
+	}
+	rows := partitionRows("orders", stats)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	columns := []column{baseColumns[0], partitionColumn, baseColumns[3], rateColumns[0]}
+	want := [][]any{
+		{"orders", int32(0), int64(10), float64(0)},
+		{"orders", int32(1), nil, nil},
+		{"orders", int32(2), int64(0), float64(0)},
+	}
+	for i, r := range rows {
+		if got := rowValues(columns, r); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("row %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// reportErrors — предупреждения, перехваченные для вывода строками отчёта
+// (--errors-as-rows), nil без флага.
+var reportErrors *errorLog
+
+// errorEntry — предупреждение в отчёте. Топик и партиция извлекаются из
+// текста (topic=..., partition=...), если он их называет.
+type errorEntry struct {
+	Topic     string `json:"topic,omitempty"`
+	Partition *int32 `json:"partition,omitempty"`
+	Error     string `json:"error"`
+}
+
+var (
+	errorTopicRe     = regexp.MustCompile(`\btopic[= ]([^\s,:()]+)`)
+	errorPartitionRe = regexp.MustCompile(`\bpartition[= ](\d+)`)
+)
+
+// errorLog подменяет вывод пакета log: строки "WARN: ..." копятся, чтобы
+// формат вывел их вместе с отчётом, остальное уходит в w как обычно.
+// После drain предупреждения снова пишутся в w: отчёт уже выведен.
+type errorLog struct {
+	mu      sync.Mutex
+	w       io.Writer
+	entries []errorEntry
+	drained bool
+}
+
+func newErrorLog(w io.Writer) *errorLog {
+	return &errorLog{w: w}
+}
+
+func (l *errorLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, msg, ok := bytes.Cut(p, []byte("WARN: "))
+	if !ok || l.drained {
+		return l.w.Write(p)
+	}
+	l.entries = append(l.entries, parseErrorEntry(string(bytes.TrimRight(msg, "\n"))))
+	return len(p), nil
+}
+
+func parseErrorEntry(msg string) errorEntry {
+	e := errorEntry{Error: msg}
+	if m := errorTopicRe.FindStringSubmatch(msg); m != nil {
+		e.Topic = m[1]
+	}
+	if m := errorPartitionRe.FindStringSubmatch(msg); m != nil {
+		if p, err := strconv.ParseInt(m[1], 10, 32); err == nil {
+			partition := int32(p)
+			e.Partition = &partition
+		}
+	}
+	return e
+}
+
+// drain возвращает накопленные предупреждения; дальше они пишутся в лог.
+// nil-получатель допустим.
+func (l *errorLog) drain() []errorEntry {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.drained = true
+	entries := l.entries
+	l.entries = nil
+	return entries
+}
+
+// release возвращает в лог предупреждения, которые не попали в отчёт
+// (запуск завершается с ошибкой до конца вывода).
+func (l *errorLog) release() {
+	for _, e := range l.drain() {
+		io.WriteString(l.w, "WARN: "+e.Error+"\n")
+	}
+}
+
+// withErrorRows добавляет формату последнюю колонку error: у строк отчёта
+// она пуста, а в конце выводится по строке на каждое предупреждение
+// с топиком и партицией, если они известны.
+func withErrorRows(f outputFormat) outputFormat {
+	newWriter := f.New
+	f.New = func(w io.Writer, header []string, opts renderOptions) rowWriter {
+		return &errorRowsWriter{
+			rowWriter: newWriter(w, append(slices.Clone(header), "error"), opts),
+			width:     len(header),
+			topic:     slices.Index(header, "topic"),
+			partition: slices.Index(header, "partition"),
+		}
+	}
+	return f
+}
+
+type errorRowsWriter struct {
+	rowWriter
+	width, topic, partition int
+}
+
+func (ew *errorRowsWriter) WriteRow(values []any) error {
+	return ew.rowWriter.WriteRow(append(values, nil))
+}
+
+func (ew *errorRowsWriter) End() error {
+	for _, e := range reportErrors.drain() {
+		row := make([]any, ew.width+1)
+		if ew.topic >= 0 && e.Topic != "" {
+			row[ew.topic] = e.Topic
+		}
+		if ew.partition >= 0 && e.Partition != nil {
+			row[ew.partition] = *e.Partition
+		}
+		row[ew.width] = e.Error
+		if err := ew.rowWriter.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return ew.rowWriter.End()
+}
+
+// errorsJSON — поле "errors" для json-object, пустая строка без
+// --errors-as-rows.
+func errorsJSON(indent string) (string, error) {
+	if reportErrors == nil {
+		return "", nil
+	}
+	entries := reportErrors.drain()
+	if len(entries) == 0 {
+		return ",\n" + indent + `"errors": []`, nil
+	}
+	buf := ",\n" + indent + `"errors": [`
+	for i, e := range entries {
+		obj, err := json.Marshal(e)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			buf += ","
+		}
+		buf += "\n" + indent + indent + string(obj)
+	}
+	return buf + "\n" + indent + "]", nil
+}
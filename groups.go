@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/IBM/sarama"
+)
+
+// groupConsumption — группа, читающая топик.
+type groupConsumption struct {
+	Group   string
+	Members int64
+	// Generation — поколение группы по метаданным участников, -1 если
+	// неизвестно (см. groupGenerations)
+	Generation int32
+	// Offsets — закоммиченные оффсеты группы по партициям топика (только >= 0)
+	Offsets map[int32]int64
+}
+
+// collectGroupConsumption для каждого топика из topicSet возвращает группы
+// с активными консьюмерами, у которых есть коммиты по этому топику, и
+// отдельно — группы с активными консьюмерами, подписанные на топик (или
+// с записями о нём), но без единого валидного коммита.
+// sortGroups == false пропускает сортировку групп (--no-sort), batchSize —
+// размер пачки групп в DescribeConsumerGroups (см. describeGroups),
+// concurrency — сколько групп опрашивать одновременно.
+func collectGroupConsumption(admin sarama.ClusterAdmin, topicSet map[string]bool, sortGroups bool, batchSize, concurrency int, retry retryPolicy) (byTopic map[string][]groupConsumption, noCommit map[string][]string) {
+	// Шаг 1: получаем список групп
+	throttle()
+	groupsMap, err := admin.ListConsumerGroups()
+	if err != nil {
+		log.Printf("WARN: failed to list consumer groups: %v", err)
+	}
+
+	var groupIDs []string
+	for g := range groupsMap {
+		groupIDs = append(groupIDs, g)
+	}
+	if sortGroups {
+		sort.Strings(groupIDs)
+	}
+
+	// Шаг 2: считаем количество активных консьюмеров в группе
+	groupConsumers := make(map[string]int64)
+	subscriptions := make(map[string]map[string]bool)
+	generations := make(map[string]int32)
+	if len(groupIDs) > 0 {
+		desc := describeGroups(admin, groupIDs, batchSize, retry)
+		groupConsumers = groupMemberCounts(desc)
+		subscriptions = groupSubscriptions(desc)
+		generations = groupGenerations(desc)
+	}
+
+	// Шаг 3: для каждой группы смотрим, какие топики она реально читает
+	// (есть коммиты offset >= 0 по хотя бы одной партиции). Группы
+	// опрашиваются параллельно, каждая пишет только в свой элемент results,
+	// общие map собираются после опроса в порядке groupIDs
+	results := make([]groupTopics, len(groupIDs))
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, g := range groupIDs {
+		consCount := groupConsumers[g]
+		if consCount == 0 {
+			// у группы нет активных consumer'ов — как в UI эти группы обычно не интересуют
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = readGroupTopics(admin, g, consCount, subscriptions[g], topicSet, retry)
+		}()
+	}
+	wg.Wait()
+
+	byTopic = make(map[string][]groupConsumption)
+	noCommit = make(map[string][]string)
+	for i, g := range groupIDs {
+		generation, ok := generations[g]
+		if !ok {
+			generation = -1
+		}
+		for topic, c := range results[i].Committed {
+			c.Generation = generation
+			byTopic[topic] = append(byTopic[topic], c)
+		}
+		for _, topic := range results[i].NoCommit {
+			noCommit[topic] = append(noCommit[topic], g)
+		}
+	}
+
+	return byTopic, noCommit
+}
+
+// groupTopics — что одна группа читает из topicSet: коммиты по топикам и
+// топики, на которые она подписана (или упоминает), но не коммитила.
+type groupTopics struct {
+	Committed map[string]groupConsumption
+	NoCommit  []string
+}
+
+// readGroupTopics опрашивает коммиты одной группы. Функция не трогает общих
+// данных, поэтому группы можно опрашивать параллельно.
+func readGroupTopics(admin sarama.ClusterAdmin, g string, members int64, subscribed, topicSet map[string]bool, retry retryPolicy) groupTopics {
+	offsetsResp, err := listGroupOffsets(admin, g, retry)
+	if err != nil {
+		log.Printf("WARN: ListConsumerGroupOffsets(group=%s): %v", g, err)
+		return groupTopics{}
+	}
+
+	result := groupTopics{Committed: make(map[string]groupConsumption)}
+	mentioned := make(map[string]bool, len(subscribed))
+	for topic := range subscribed {
+		mentioned[topic] = true
+	}
+	for topic, partMap := range offsetsResp.Blocks {
+		// нас интересуют только наши business-топики
+		if !topicSet[topic] {
+			continue
+		}
+		mentioned[topic] = true
+		offsets := committedOffsets(partMap)
+		if len(offsets) == 0 {
+			continue
+		}
+		// эта группа реально читает этот топик
+		result.Committed[topic] = groupConsumption{
+			Group:   g,
+			Members: members,
+			Offsets: offsets,
+		}
+	}
+
+	// консьюмеры есть, а коммитов нет: только что запущенная или
+	// неправильно настроенная группа
+	for topic := range mentioned {
+		if _, ok := result.Committed[topic]; topicSet[topic] && !ok {
+			result.NoCommit = append(result.NoCommit, topic)
+		}
+	}
+	return result
+}
+
+// describeGroups описывает группы (см. describeGroupBatches). Группы, чей
+// координатор ещё загружает оффсеты, описываются повторно, пока не пройдёт
+// retry.LoadTimeout; после этого их описание остаётся с ошибкой.
+func describeGroups(admin sarama.ClusterAdmin, groupIDs []string, batchSize int, retry retryPolicy) []*sarama.GroupDescription {
+	desc := describeGroupBatches(admin, groupIDs, batchSize, retry)
+	deadline := time.Now().Add(retry.LoadTimeout)
+	for {
+		var loading []string
+		byGroup := make(map[string]int, len(desc))
+		for i, d := range desc {
+			if d != nil && errors.Is(d.Err, sarama.ErrOffsetsLoadInProgress) {
+				loading = append(loading, d.GroupId)
+				byGroup[d.GroupId] = i
+			}
+		}
+		if len(loading) == 0 || time.Now().Add(retry.Backoff).After(deadline) {
+			return desc
+		}
+		log.Printf("WARN: coordinators of %d groups are loading offsets, retrying in %s", len(loading), retry.Backoff)
+		time.Sleep(retry.Backoff)
+		for _, d := range describeGroupBatches(admin, loading, batchSize, retry) {
+			if d == nil {
+				continue
+			}
+			if i, ok := byGroup[d.GroupId]; ok {
+				desc[i] = d
+			}
+		}
+	}
+}
+
+// describeGroupBatches описывает группы пачками по batchSize штук, пачки
+// запрашиваются параллельно. Группы, которые помещаются в одну пачку (или
+// batchSize <= 0), описываются одним запросом. Пачка с ошибкой повторяется
+// по retry; если попытки кончились, её группы пропускаются с предупреждением.
+func describeGroupBatches(admin sarama.ClusterAdmin, groupIDs []string, batchSize int, retry retryPolicy) []*sarama.GroupDescription {
+	if batchSize <= 0 || batchSize > len(groupIDs) {
+		batchSize = len(groupIDs)
+	}
+	batches := slices.Collect(slices.Chunk(groupIDs, batchSize))
+	results := make([][]*sarama.GroupDescription, len(batches))
+
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := 0; ; attempt++ {
+				throttle()
+				desc, err := admin.DescribeConsumerGroups(batch)
+				if err == nil {
+					results[i] = desc
+					return
+				}
+				if attempt >= retry.Retries {
+					log.Printf("WARN: DescribeConsumerGroups(%d groups from %s): %v", len(batch), batch[0], err)
+					return
+				}
+				log.Printf("WARN: DescribeConsumerGroups(%d groups from %s) attempt %d/%d failed: %v, retrying in %s", len(batch), batch[0], attempt+1, retry.Retries+1, err, retry.Backoff)
+				time.Sleep(retry.Backoff)
+			}
+		}()
+	}
+	wg.Wait()
+	return slices.Concat(results...)
+}
+
+// retryPolicy — сколько раз и с какой паузой повторять запрос.
+type retryPolicy struct {
+	Retries int
+	Backoff time.Duration
+
+	// LoadTimeout — сколько повторять запрос, пока координатор группы
+	// загружает оффсеты; такие повторы не входят в Retries
+	LoadTimeout time.Duration
+}
+
+// listGroupOffsets запрашивает коммиты группы, повторяя запрос при временных
+// ошибках: координатор группы недоступен или переезжает, обрыв соединения.
+// Пока координатор загружает оффсеты (сразу после перезапуска брокера),
+// запрос повторяется до retry.LoadTimeout. Ошибки авторизации и прочие
+// ответы брокера не повторяются.
+func listGroupOffsets(admin sarama.ClusterAdmin, group string, retry retryPolicy) (*sarama.OffsetFetchResponse, error) {
+	deadline := time.Now().Add(retry.LoadTimeout)
+	for attempt := 0; ; {
+		throttle()
+		resp, err := admin.ListConsumerGroupOffsets(group, nil)
+		if err == nil && !errors.Is(resp.Err, sarama.ErrNoError) {
+			err = resp.Err
+		}
+		if errors.Is(err, sarama.ErrOffsetsLoadInProgress) && time.Now().Add(retry.Backoff).Before(deadline) {
+			log.Printf("WARN: ListConsumerGroupOffsets(group=%s): coordinator is loading offsets, retrying in %s", group, retry.Backoff)
+			time.Sleep(retry.Backoff)
+			continue
+		}
+		attempt++
+		if err == nil || attempt > retry.Retries || !retryableGroupError(err) {
+			return resp, err
+		}
+		log.Printf("WARN: ListConsumerGroupOffsets(group=%s) attempt %d/%d failed: %v, retrying in %s", group, attempt, retry.Retries+1, err, retry.Backoff)
+		time.Sleep(retry.Backoff)
+	}
+}
+
+// retryableGroupError отличает временные ошибки запроса к координатору
+// группы от окончательных. Загрузка оффсетов координатором повторяется
+// отдельно, по времени (retryPolicy.LoadTimeout). Ошибки не из протокола Kafka (сеть, таймауты
+// клиента) считаются временными.
+func retryableGroupError(err error) bool {
+	var kerr sarama.KError
+	if !errors.As(err, &kerr) {
+		return true
+	}
+	switch kerr {
+	case sarama.ErrConsumerCoordinatorNotAvailable,
+		sarama.ErrNotCoordinatorForConsumer,
+		sarama.ErrRequestTimedOut,
+		sarama.ErrNetworkException:
+		return true
+	default:
+		return false
+	}
+}
+
+// committedOffsets оставляет валидные коммиты (offset >= 0) по партициям.
+func committedOffsets(blocks map[int32]*sarama.OffsetFetchResponseBlock) map[int32]int64 {
+	offsets := make(map[int32]int64)
+	for p, block := range blocks {
+		if block == nil {
+			continue
+		}
+		if block.Offset >= 0 {
+			offsets[p] = block.Offset
+		}
+	}
+	return offsets
+}
+
+// groupTopicOffsets возвращает коммиты группы по топикам, которые она читает
+// (есть хотя бы один валидный коммит), независимо от наличия консьюмеров.
+func groupTopicOffsets(admin sarama.ClusterAdmin, group string, retry retryPolicy) (map[string]groupConsumption, error) {
+	resp, err := listGroupOffsets(admin, group, retry)
+	if err != nil {
+		return nil, err
+	}
+	byTopic := make(map[string]groupConsumption)
+	for topic, blocks := range resp.Blocks {
+		if offsets := committedOffsets(blocks); len(offsets) > 0 {
+			byTopic[topic] = groupConsumption{Group: group, Offsets: offsets}
+		}
+	}
+	return byTopic, nil
+}
+
+// groupSubscriptions возвращает топики, на которые подписаны участники
+// каждой группы, по метаданным из DescribeConsumerGroups. Группы с ошибкой
+// в ответе и участники с нераспознанными метаданными пропускаются.
+func groupSubscriptions(desc []*sarama.GroupDescription) map[string]map[string]bool {
+	subs := make(map[string]map[string]bool, len(desc))
+	for _, d := range desc {
+		if d == nil || !errors.Is(d.Err, sarama.ErrNoError) {
+			continue
+		}
+		for _, m := range d.Members {
+			meta, err := m.GetMemberMetadata()
+			if err != nil || meta == nil {
+				continue
+			}
+			for _, topic := range meta.Topics {
+				if subs[d.GroupId] == nil {
+					subs[d.GroupId] = make(map[string]bool)
+				}
+				subs[d.GroupId][topic] = true
+			}
+		}
+	}
+	return subs
+}
+
+// groupGenerations возвращает поколение (generation id) групп. В ответе
+// DescribeGroups поколения нет, поэтому оно берётся из метаданных подписки
+// участников: начиная с версии 2 (KIP-429, Kafka 2.4+) клиент передаёт в них
+// поколение, в котором получил текущее назначение. Берётся максимум по
+// участникам; группы, где ни один участник не прислал метаданные v2+
+// (старые клиенты, не-Java клиенты), в результат не попадают.
+func groupGenerations(desc []*sarama.GroupDescription) map[string]int32 {
+	generations := make(map[string]int32, len(desc))
+	for _, d := range desc {
+		if d == nil || !errors.Is(d.Err, sarama.ErrNoError) {
+			continue
+		}
+		for _, m := range d.Members {
+			meta, err := m.GetMemberMetadata()
+			if err != nil || meta == nil || meta.Version < 2 || meta.GenerationID < 0 {
+				continue
+			}
+			if gen, ok := generations[d.GroupId]; !ok || meta.GenerationID > gen {
+				generations[d.GroupId] = meta.GenerationID
+			}
+		}
+	}
+	return generations
+}
+
+// groupMemberCounts возвращает число участников каждой группы. Ошибка в
+// ответе по отдельной группе не портит остальные: такая группа пропускается.
+func groupMemberCounts(desc []*sarama.GroupDescription) map[string]int64 {
+	counts := make(map[string]int64, len(desc))
+	for _, d := range desc {
+		if d == nil {
+			continue
+		}
+		if !errors.Is(d.Err, sarama.ErrNoError) {
+			log.Printf("WARN: DescribeConsumerGroups(group=%s): %v", d.GroupId, d.Err)
+			continue
+		}
+		// активные consumers = кол-во членов
+		counts[d.GroupId] = int64(len(d.Members))
+	}
+	return counts
+}
+
+// consumerCount — число активных консьюмеров во всех группах, читающих топик.
+func consumerCount(groups []groupConsumption) int64 {
+	var n int64
+	for _, g := range groups {
+		n += g.Members
+	}
+	return n
+}
+
+var flattenedGroupsHeader = []string{"topic", "group", "members", "lag"}
+
+// groupLag — суммарное отставание группы по партициям топика, для которых
+// известны и коммит группы, и high watermark.
+func groupLag(g groupConsumption, latest map[int32]int64) int64 {
+	var lag int64
+	for p, committed := range g.Offsets {
+		if hw, ok := latest[p]; ok && hw > committed {
+			lag += hw - committed
+		}
+	}
+	return lag
+}
+
+// topicLag — суммарное отставание групп, читающих топик. Каждая группа
+// входит в groups один раз со своими коммитами по партициям, поэтому
+// партиция учитывается для группы не больше одного раза.
+func topicLag(groups []groupConsumption, latest map[int32]int64) int64 {
+	var lag int64
+	for _, g := range groups {
+		lag += groupLag(g, latest)
+	}
+	return lag
+}
+
+// stalestCommitAge — давность последнего коммита по топику у самой отстающей
+// из читающих групп; -1, если время коммита не известно ни для одной группы
+// или партиция __consumer_offsets хотя бы одной из групп не дочитана (тогда
+// самую отстающую группу не определить). since — начало окна чтения коммитов
+// (см. lastCommitTimes): группа без коммита в дочитанной партиции считается
+// коммитившей в начале окна. Нулевое since — лог прочитан целиком, такая
+// группа пропускается.
+func stalestCommitAge(topic string, groups []groupConsumption, scan commitScan, since time.Time) time.Duration {
+	age := time.Duration(-1)
+	for _, g := range groups {
+		if !scan.scanned(g.Group) {
+			return -1
+		}
+		at, ok := scan.Commits[groupTopic{g.Group, topic}]
+		if !ok && since.IsZero() {
+			continue
+		}
+		if !ok {
+			at = since
+		}
+		if d := time.Since(at); d > age {
+			age = d
+		}
+	}
+	return age
+}
+
+const consumerOffsetsTopic = "__consumer_offsets"
+
+// commitScanIdleTimeout — сколько ждать следующего сообщения при чтении
+// __consumer_offsets, прежде чем считать партицию дочитанной: хвост лога
+// может состоять из записей, которые консьюмер не отдаёт.
+const commitScanIdleTimeout = 5 * time.Second
+
+type groupTopic struct {
+	Group string
+	Topic string
+}
+
+// commitScan — результат чтения __consumer_offsets (см. lastCommitTimes).
+type commitScan struct {
+	// Commits — время последнего коммита группы по топику
+	Commits map[groupTopic]time.Time
+
+	// Scanned — партиции, прочитанные до high watermark; Partitions — число
+	// партиций топика, по нему партиция группы находится через groupPartition
+	Scanned    map[int32]bool
+	Partitions int
+}
+
+// scanned сообщает, дочитана ли партиция, в которой координатор хранит
+// коммиты группы. Нулевой commitScan (чтение не удалось) не дочитан.
+func (s commitScan) scanned(group string) bool {
+	return s.Partitions > 0 && s.Scanned[groupPartition(group, s.Partitions)]
+}
+
+// lastCommitTimes возвращает время последнего коммита каждой группы из groups
+// по каждому топику. OffsetFetch время коммита не отдаёт, поэтому оно берётся
+// из самого лога __consumer_offsets; читаются только партиции, в которых
+// координатор хранит коммиты нужных групп, и только записи не старше since
+// (оффсет находится по времени). Нулевое since — лог читается целиком.
+// Партиции, которые не удалось дочитать, в Scanned не попадают.
+func lastCommitTimes(client sarama.Client, groups map[string]bool, since time.Time) (commitScan, error) {
+	partitions, err := client.Partitions(consumerOffsetsTopic)
+	if err != nil {
+		return commitScan{}, err
+	}
+	wanted := make(map[int32]bool)
+	for g := range groups {
+		wanted[groupPartition(g, len(partitions))] = true
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return commitScan{}, err
+	}
+	defer consumer.Close()
+
+	scan := commitScan{
+		Commits:    make(map[groupTopic]time.Time),
+		Scanned:    make(map[int32]bool, len(wanted)),
+		Partitions: len(partitions),
+	}
+	for p := range wanted {
+		throttle()
+		hw, err := client.GetOffset(consumerOffsetsTopic, p, sarama.OffsetNewest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Newest) topic=%s partition=%d: %v", consumerOffsetsTopic, p, err)
+			continue
+		}
+		if hw <= 0 {
+			scan.Scanned[p] = true
+			continue
+		}
+		at := sarama.OffsetOldest
+		if !since.IsZero() {
+			at = since.UnixMilli()
+		}
+		throttle()
+		start, err := client.GetOffset(consumerOffsetsTopic, p, at)
+		if err != nil {
+			log.Printf("WARN: GetOffset(%s) topic=%s partition=%d: %v", offsetName(at), consumerOffsetsTopic, p, err)
+			continue
+		}
+		if start < 0 || start >= hw {
+			// коммитов в окне нет
+			scan.Scanned[p] = true
+			continue
+		}
+		pc, err := consumer.ConsumePartition(consumerOffsetsTopic, p, start)
+		if err != nil {
+			log.Printf("WARN: consume %s partition=%d: %v", consumerOffsetsTopic, p, err)
+			continue
+		}
+		next := scanCommits(pc, start, hw, groups, scan.Commits)
+		pc.Close()
+		if next >= hw {
+			scan.Scanned[p] = true
+			continue
+		}
+		// хвост из маркеров транзакционных коммитов консьюмер не отдаёт
+		if control, err := controlTail(client, consumerOffsetsTopic, p, next, hw); err != nil || !control {
+			log.Printf("WARN: topic=%s partition=%d: commit scan stopped at offset %d before the high watermark %d, last_commit_age of its groups is left empty", consumerOffsetsTopic, p, next, hw)
+			continue
+		}
+		scan.Scanned[p] = true
+	}
+	return scan, nil
+}
+
+// scanCommits читает коммиты из pc до оффсета hw или до тишины дольше
+// commitScanIdleTimeout и возвращает оффсет, следующий за последней
+// прочитанной записью (start, если не пришло ни одной).
+func scanCommits(pc sarama.PartitionConsumer, start, hw int64, groups map[string]bool, commits map[groupTopic]time.Time) int64 {
+	next := start
+	idle := time.NewTimer(commitScanIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case msg := <-pc.Messages():
+			// value == nil — tombstone, оффсет группы удалён
+			if group, topic, ok := decodeOffsetCommitKey(msg.Key); ok && groups[group] && msg.Value != nil {
+				key := groupTopic{group, topic}
+				if msg.Timestamp.After(commits[key]) {
+					commits[key] = msg.Timestamp
+				}
+			}
+			next = msg.Offset + 1
+			if next >= hw {
+				return next
+			}
+			idle.Reset(commitScanIdleTimeout)
+		case <-idle.C:
+			return next
+		}
+	}
+}
+
+// groupPartition повторяет выбор партиции __consumer_offsets координатором:
+// abs(groupId.hashCode()) % partitions, где hashCode — String.hashCode из Java.
+func groupPartition(group string, partitions int) int32 {
+	var h int32
+	for _, c := range utf16.Encode([]rune(group)) {
+		h = 31*h + int32(c)
+	}
+	if h == math.MinInt32 {
+		h = 0
+	} else if h < 0 {
+		h = -h
+	}
+	return int32(int(h) % partitions)
+}
+
+// decodeOffsetCommitKey разбирает ключ записи __consumer_offsets. Ключи
+// версий 0 и 1 — коммиты оффсетов (group, topic, partition), версия 2 —
+// метаданные группы, они пропускаются.
+func decodeOffsetCommitKey(key []byte) (group, topic string, ok bool) {
+	if len(key) < 2 {
+		return "", "", false
+	}
+	if version := binary.BigEndian.Uint16(key); version > 1 {
+		return "", "", false
+	}
+	rest := key[2:]
+	if group, rest, ok = readKafkaString(rest); !ok {
+		return "", "", false
+	}
+	if topic, rest, ok = readKafkaString(rest); !ok {
+		return "", "", false
+	}
+	return group, topic, len(rest) >= 4
+}
+
+func readKafkaString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(int16(binary.BigEndian.Uint16(b)))
+	if n < 0 || len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
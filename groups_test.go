@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+func TestGroupMemberCounts(t *testing.T) {
+	desc := []*sarama.GroupDescription{
+		{GroupId: "svc-a", Members: map[string]*sarama.GroupMemberDescription{"m1": {}, "m2": {}}},
+		{GroupId: "svc-empty", State: "Empty"},
+		nil,
+		{GroupId: "svc-broken", Err: sarama.ErrConsumerCoordinatorNotAvailable, Members: map[string]*sarama.GroupMemberDescription{"m1": {}}},
+	}
+	want := map[string]int64{"svc-a": 2, "svc-empty": 0}
+	if got := groupMemberCounts(desc); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupMemberCounts() = %v, want %v", got, want)
+	}
+}
+
+// fakeAdmin — ClusterAdmin для тестов: группы с заданными членами и
+// коммитами; координатор группы может отвечать «загружаю оффсеты» заданное
+// число раз, ListConsumerGroupOffsets может сначала вернуть ошибки из очереди.
+type fakeAdmin struct {
+	sarama.ClusterAdmin
+
+	mu      sync.Mutex
+	members map[string]int
+	offsets map[string]map[string]map[int32]int64
+	loading map[string]int
+	errs    map[string][]error
+
+	// extraNil — добавлять nil в ответы DescribeConsumerGroups
+	extraNil bool
+
+	describeCalls map[string]int
+	offsetCalls   map[string]int
+}
+
+func (a *fakeAdmin) ListConsumerGroups() (map[string]string, error) {
+	groups := make(map[string]string, len(a.members))
+	for g := range a.members {
+		groups[g] = "consumer"
+	}
+	return groups, nil
+}
+
+func (a *fakeAdmin) DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.describeCalls == nil {
+		a.describeCalls = make(map[string]int)
+	}
+	var desc []*sarama.GroupDescription
+	if a.extraNil {
+		desc = append(desc, nil)
+	}
+	for _, g := range groups {
+		a.describeCalls[g]++
+		d := &sarama.GroupDescription{GroupId: g, State: "Stable", Members: make(map[string]*sarama.GroupMemberDescription)}
+		if a.loading[g] > 0 {
+			a.loading[g]--
+			d.Err = sarama.ErrOffsetsLoadInProgress
+			d.State = ""
+		}
+		for i := range a.members[g] {
+			d.Members[fmt.Sprintf("%s-m%d", g, i)] = &sarama.GroupMemberDescription{}
+		}
+		desc = append(desc, d)
+	}
+	return desc, nil
+}
+
+func (a *fakeAdmin) ListConsumerGroupOffsets(group string, _ map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.offsetCalls == nil {
+		a.offsetCalls = make(map[string]int)
+	}
+	a.offsetCalls[group]++
+	if errs := a.errs[group]; len(errs) > 0 {
+		a.errs[group] = errs[1:]
+		return nil, errs[0]
+	}
+	resp := &sarama.OffsetFetchResponse{Blocks: make(map[string]map[int32]*sarama.OffsetFetchResponseBlock)}
+	if a.loading[group] > 0 {
+		a.loading[group]--
+		resp.Err = sarama.ErrOffsetsLoadInProgress
+		return resp, nil
+	}
+	for topic, parts := range a.offsets[group] {
+		resp.Blocks[topic] = make(map[int32]*sarama.OffsetFetchResponseBlock, len(parts))
+		for p, off := range parts {
+			resp.Blocks[topic][p] = &sarama.OffsetFetchResponseBlock{Offset: off}
+		}
+	}
+	return resp, nil
+}
+
+var testRetry = retryPolicy{Retries: 2, Backoff: time.Millisecond, LoadTimeout: time.Second}
+
+func TestDescribeGroupsRetriesLoadingCoordinator(t *testing.T) {
+	admin := &fakeAdmin{
+		members:  map[string]int{"svc-a": 2, "svc-b": 1},
+		loading:  map[string]int{"svc-a": 2},
+		extraNil: true,
+	}
+	desc := describeGroups(admin, []string{"svc-a", "svc-b"}, 0, testRetry)
+
+	got := groupMemberCounts(desc)
+	want := map[string]int64{"svc-a": 2, "svc-b": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("member counts = %v, want %v", got, want)
+	}
+	if n := admin.describeCalls["svc-a"]; n != 3 {
+		t.Errorf("svc-a described %d times, want 3", n)
+	}
+	if n := admin.describeCalls["svc-b"]; n != 1 {
+		t.Errorf("svc-b described %d times, want 1", n)
+	}
+}
+
+func TestDescribeGroupsGivesUpAfterLoadTimeout(t *testing.T) {
+	admin := &fakeAdmin{
+		members: map[string]int{"svc-a": 1},
+		loading: map[string]int{"svc-a": 1 << 20},
+	}
+	retry := retryPolicy{Backoff: time.Millisecond, LoadTimeout: 20 * time.Millisecond}
+	desc := describeGroups(admin, []string{"svc-a"}, 0, retry)
+	if len(desc) != 1 || !errors.Is(desc[0].Err, sarama.ErrOffsetsLoadInProgress) {
+		t.Fatalf("describeGroups() = %v, want svc-a still loading", desc)
+	}
+}
+
+func TestListGroupOffsetsWaitsForLoadingCoordinator(t *testing.T) {
+	admin := &fakeAdmin{
+		loading: map[string]int{"svc-a": 2},
+		offsets: map[string]map[string]map[int32]int64{"svc-a": {"orders": {0: 42}}},
+	}
+	resp, err := listGroupOffsets(admin, "svc-a", testRetry)
+	if err != nil {
+		t.Fatalf("listGroupOffsets() error = %v", err)
+	}
+	if off := resp.Blocks["orders"][0].Offset; off != 42 {
+		t.Errorf("offset = %d, want 42", off)
+	}
+	if n := admin.offsetCalls["svc-a"]; n != 3 {
+		t.Errorf("ListConsumerGroupOffsets called %d times, want 3", n)
+	}
+}
+
+func TestListGroupOffsetsRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", nil, 1, nil},
+		{"transient then success", []error{sarama.ErrNotCoordinatorForConsumer}, 2, nil},
+		{"network error then success", []error{io.EOF}, 2, nil},
+		{"retries exhausted", []error{sarama.ErrRequestTimedOut, sarama.ErrRequestTimedOut, sarama.ErrRequestTimedOut}, 3, sarama.ErrRequestTimedOut},
+		{"not retryable", []error{sarama.ErrGroupAuthorizationFailed}, 1, sarama.ErrGroupAuthorizationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin := &fakeAdmin{
+				offsets: map[string]map[string]map[int32]int64{"svc-a": {"orders": {0: 7}}},
+				errs:    map[string][]error{"svc-a": tt.errs},
+			}
+			_, err := listGroupOffsets(admin, "svc-a", testRetry)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("listGroupOffsets() error = %v, want %v", err, tt.wantErr)
+			}
+			if n := admin.offsetCalls["svc-a"]; n != tt.wantCalls {
+				t.Errorf("ListConsumerGroupOffsets called %d times, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCollectGroupConsumptionDeterministicOrder(t *testing.T) {
+	const groups = 64
+	admin := &fakeAdmin{
+		members: make(map[string]int),
+		offsets: make(map[string]map[string]map[int32]int64),
+	}
+	var want []string
+	for i := range groups {
+		g := fmt.Sprintf("svc-%02d", i)
+		admin.offsets[g] = map[string]map[int32]int64{"orders": {0: int64(i)}, "internal": {0: 1}}
+		if i%5 == 0 {
+			// без активных консьюмеров группа в отчёт не попадает
+			continue
+		}
+		admin.members[g] = 1 + i%3
+		want = append(want, g)
+	}
+	topicSet := map[string]bool{"orders": true}
+
+	for run := range 5 {
+		byTopic, _ := collectGroupConsumption(admin, topicSet, true, 7, 16, testRetry)
+		if len(byTopic) != 1 {
+			t.Fatalf("run %d: topics = %v, want only orders", run, byTopic)
+		}
+		var got []string
+		for _, c := range byTopic["orders"] {
+			got = append(got, c.Group)
+			if c.Members != int64(admin.members[c.Group]) {
+				t.Errorf("run %d: %s members = %d, want %d", run, c.Group, c.Members, admin.members[c.Group])
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: groups = %v, want %v", run, got, want)
+		}
+	}
+}
+
+func TestStalestCommitAge(t *testing.T) {
+	now := time.Now()
+	groups := []groupConsumption{{Group: "svc-a"}, {Group: "svc-b"}}
+	scan := commitScan{
+		Commits:    map[groupTopic]time.Time{{"svc-a", "orders"}: now.Add(-time.Minute)},
+		Scanned:    map[int32]bool{0: true, 1: true, 2: true},
+		Partitions: 3,
+	}
+	within := func(got, want time.Duration) bool { return got >= want && got < want+time.Second }
+
+	if got := stalestCommitAge("orders", groups, scan, time.Time{}); !within(got, time.Minute) {
+		t.Errorf("whole log: age = %s, want 1m (svc-b unknown)", got)
+	}
+	if got := stalestCommitAge("orders", groups, scan, now.Add(-time.Hour)); !within(got, time.Hour) {
+		t.Errorf("1h window: age = %s, want 1h (svc-b has no commit in the window)", got)
+	}
+	if got := stalestCommitAge("payments", nil, scan, now.Add(-time.Hour)); got != -1 {
+		t.Errorf("no readers: age = %s, want -1", got)
+	}
+}
+
+func TestStalestCommitAgeIncompleteScan(t *testing.T) {
+	now := time.Now()
+	groups := []groupConsumption{{Group: "svc-a"}, {Group: "svc-b"}}
+	commits := map[groupTopic]time.Time{{"svc-a", "orders"}: now.Add(-time.Minute)}
+	const partitions = 50
+	pa, pb := groupPartition("svc-a", partitions), groupPartition("svc-b", partitions)
+	if pa == pb {
+		t.Fatalf("svc-a and svc-b share partition %d", pa)
+	}
+	since := now.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		scan commitScan
+	}{
+		// lastCommitTimes вернул ошибку: в main остаётся нулевой commitScan
+		{"scan error", commitScan{}},
+		{"partition of svc-b skipped", commitScan{Commits: commits, Scanned: map[int32]bool{pa: true}, Partitions: partitions}},
+		{"partition of svc-a skipped", commitScan{Commits: commits, Scanned: map[int32]bool{pb: true}, Partitions: partitions}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, since := range []time.Time{{}, since} {
+				if got := stalestCommitAge("orders", groups, tt.scan, since); got != -1 {
+					t.Errorf("since %v: age = %s, want -1", since, got)
+				}
+			}
+		})
+	}
+}
@@ -3,94 +3,997 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
+	"golang.org/x/time/rate"
 )
 
-type topicStats struct {
-	Partitions int32
-	Messages   int64
-}
-
 func main() {
 	var (
-		brokersStr      string
-		businessRegexp  string
-		topicGrep       string
-		kafkaVersionStr string
-		logVerbose      bool
+		brokersStr            string
+		businessRegexp        string
+		internalPrefix        string
+		topicGrep             string
+		kafkaVersionStr       string
+		versionFallback       bool
+		logVerbose            bool
+		saslMechanism         string
+		securityProtocol      string
+		commandConfigPath     string
+		tlsServerName         string
+		rackID                string
+		awsRegion             string
+		connectRetries        int
+		connectBackoff        time.Duration
+		groupOffsetRetry      retryPolicy
+		expectedPartitionsStr string
+		format                string
+		stream                bool
+		listFormats           bool
+		printSchema           bool
+		reassignTemplate      bool
+		consumersFilter       string
+		skewMetric            string
+		sqlTable              string
+		sqlCreateTable        bool
+		activePartitions      bool
+		withPercentiles       bool
+		errorsAsRows          bool
+		overPartitioned       float64
+		neverWritten          bool
+		onlyNeverWritten      bool
+		batchOffsets          bool
+		rateInterval          time.Duration
+		topicsSpec            string
+		topicsFile            string
+		category              bool
+		changelogSuffixes     string
+		repartitionSuffixes   string
+		totals                bool
+		human                 bool
+		nullString            string
+		jsonFieldMap          string
+		headerMap             string
+		assertSpec            string
+		report                string
+		autoDetailSkew        int64
+		outputPath            string
+		tee                   bool
+		singleReplica         bool
+		minISR                bool
+		rateDetail            bool
+		replicaAssignment     bool
+		leaderEpoch           bool
+		replicaLag            bool
+		replicationLag        bool
+		preferredLeader       bool
+		leaderBrokerAddrs     bool
+		noSort                bool
+		stable                bool
+		sortBy                string
+		sortDesc              bool
+		limit                 int
+		collapseDepth         int
+		cacheFile             string
+		configKeys            string
+		histogramBuckets      string
+		groupDescribeBatch    int
+		groupConcurrency      int
+		convention            string
+		strict                bool
+		cacheMaxAge           time.Duration
+		inventoryPath         string
+		configBaselinePath    string
+		commitAge             bool
+		commitAgeLookback     time.Duration
+		oldestMessageAge      bool
+		groupsNoCommit        bool
+		groupCount            bool
+		totalLag              bool
+		withTimestamp         bool
+		transpose             bool
+		allowNoCluster        bool
+		group                 string
+		excludeControl        bool
+		countMode             string
+		rateLimit             float64
+		flattenGroups         bool
+		messageWindow         time.Duration
+		topicTimeout          time.Duration
+		atTimestamp           string
+		metadataRetries       int
+		metadataBackoff       time.Duration
+		metadataRefresh       time.Duration
+		metadataFull          bool
+		minPartitions         int
+		maxPartitions         int
+		saslCreds             saslCredentials
+		gssapi                gssapiOptions
 	)
 
 	flag.StringVar(&brokersStr, "brokers", "localhost:9092", "Comma-separated list of Kafka brokers")
-	flag.StringVar(&businessRegexp, "business-regexp", "^[^_].*", "Regexp for business topics (default: not starting with __)")
+	flag.StringVar(&businessRegexp, "business-regexp", "^[^_].*", "Regexp for business topics; when set explicitly, --internal-prefix is ignored (default: not starting with --internal-prefix)")
+	flag.StringVar(&internalPrefix, "internal-prefix", "_", "Topics starting with this prefix are internal and skipped unless --business-regexp is set explicitly (e.g. .internal.; empty: no internal topics)")
 	flag.StringVar(&topicGrep, "topic-grep", "", "Optional substring filter for topic names")
+	flag.StringVar(&topicsSpec, "topics", "", "Report only these topics: comma-separated list, or - to read names from stdin, one per line (other filters still apply)")
+	flag.StringVar(&topicsFile, "topics-file", "", "File with topic glob patterns (e.g. orders-*), one per line; matches are added to --topics")
+	flag.StringVar(&consumersFilter, "consumers", "", "Only report topics whose consumer count matches op:N, where op is eq, ne, gt, ge, lt or le (e.g. eq:0 for topics nobody reads)")
+	flag.IntVar(&minPartitions, "min-partitions", 0, "Only report topics with at least N partitions (0 = no limit)")
+	flag.IntVar(&maxPartitions, "max-partitions", 0, "Only report topics with at most N partitions (0 = no limit)")
 	flag.StringVar(&kafkaVersionStr, "kafka-version", "2.7.0", "Kafka protocol version (e.g. 2.7.0, 2.8.0, 3.4.0)")
+	flag.BoolVar(&versionFallback, "version-fallback", false, "Use Kafka protocol version 2.7.0 with a warning instead of exiting when --kafka-version is unsupported")
+	flag.StringVar(&expectedPartitionsStr, "expected-partitions", "", "Expected partition counts as topic=N,... (adds partitions_expected,matches columns)")
+	flag.StringVar(&report, "report", "topics", "Report to build: "+strings.Join(reportModes, ", "))
+	flag.StringVar(&inventoryPath, "inventory", "", "File with sanctioned topic names, one per line (for --report shadow)")
+	flag.StringVar(&format, "format", "csv", "Output format (see --list-formats); defaults to $KAFKA_REPORT_FORMAT if set")
+	flag.BoolVar(&human, "human", false, "Show message counts, lags and rates with SI suffixes (1.5G) in csv output; identifiers such as broker_id and partition stay exact, json stays numeric")
+	flag.StringVar(&assertSpec, "assert", "", "Comma-separated checks topic=partitions:N, topic=min-replication:N or topic=messages>0; exit with an error listing failures after the report")
+	flag.StringVar(&headerMap, "header-map", "", "Rename columns in the CSV header line only, e.g. partitions=num_partitions,messages=message_count")
+	flag.StringVar(&sqlTable, "table", "kafka_topics", "Table name for --format sql")
+	flag.BoolVar(&sqlCreateTable, "sql-create-table", false, "With --format sql, start with CREATE TABLE IF NOT EXISTS for the output columns")
+	flag.StringVar(&jsonFieldMap, "json-field-map", "", "Rename fields in JSON formats, e.g. topic=name,messages=count")
+	flag.StringVar(&nullString, "null-string", "", "How missing values are written in CSV (e.g. NULL or -); JSON formats always use null")
+	flag.StringVar(&outputPath, "output", "", "Write the report to this file instead of stdout")
+	flag.BoolVar(&tee, "tee", false, "With --output, also write the report to stdout")
+	flag.BoolVar(&listFormats, "list-formats", false, "Print supported output formats and exit")
+	flag.BoolVar(&reassignTemplate, "emit-reassignment-template", false, "Print the current replica assignment of the selected topics as kafka-reassign-partitions.sh JSON instead of the report and exit")
+	flag.BoolVar(&printSchema, "print-schema", false, "Print a JSON Schema of the topics report row for the enabled columns and exit")
+	flag.BoolVar(&errorsAsRows, "errors-as-rows", false, "Emit warnings in the report instead of the log: an errors array in json-object, a trailing error column with one row per warning in csv, json and jsonl")
+	flag.BoolVar(&stable, "stable", false, "Diff-friendly output for snapshots kept in version control: deterministic order and no run metadata (generated_at in json-object, influx timestamps); collected_at still appears with --with-timestamp")
+	flag.BoolVar(&noSort, "no-sort", false, "Skip sorting topics and groups; row order is then non-deterministic (useful with --stream on very large clusters)")
+	flag.StringVar(&sortBy, "sort", "", "Sort topics by the value of this output column (e.g. messages); empty values go last")
+	flag.BoolVar(&sortDesc, "sort-desc", false, "With --sort, sort in descending order")
+	flag.IntVar(&collapseDepth, "collapse-prefix-depth", 0, "Merge topics sharing the first N dot-separated name segments into one row summing partitions, consumers, messages and msgs_per_sec (0 disables)")
+	flag.IntVar(&limit, "limit", 0, "Output only the first N topics after sorting (0 means unlimited)")
+	flag.StringVar(&convention, "convention", "", "With --report naming, regexp that compliant topic names must match")
+	flag.BoolVar(&strict, "strict", false, "With --report naming or config-drift, exit with an error when any topic violates the convention or drifts from the baseline")
+	flag.StringVar(&configBaselinePath, "config-baseline", "", "With --report config-drift, JSON file with expected topic configs: {\"*\": {key: value}, \"topic\": {key: value}}; only these keys are checked")
+	flag.StringVar(&histogramBuckets, "histogram-buckets", "1000,1000000", "With --report histogram, increasing upper bounds of the message count buckets; empty topics and topics above the last bound get their own buckets")
+	flag.StringVar(&configKeys, "config-keys", "", "With --report broker-config, show only these comma-separated config keys")
+	flag.StringVar(&cacheFile, "cache-file", "", "Render the topics report from this snapshot file if it is fresh, otherwise collect from the cluster and write it")
+	flag.DurationVar(&cacheMaxAge, "cache-max-age", 0, "With --cache-file, collect again when the snapshot is older than this (0 means never expires)")
+	flag.BoolVar(&flattenGroups, "flatten-groups", false, "Write one topic,group,members,lag row per consuming group instead of per-topic rows")
+	flag.BoolVar(&stream, "stream", false, "Write each row as soon as its topic is processed (csv, jsonl); rows are emitted in collection order and never re-sorted")
+	flag.DurationVar(&topicTimeout, "topic-timeout", 0, "Give up collecting offsets of a topic after this long, emit it marked incomplete and move on; 0 disables")
+	flag.Float64Var(&rateLimit, "rate-limit", 0, "Limit offset, admin and describe requests to the cluster to N per second across all brokers; metadata and record reads are not limited (0 means unlimited)")
+	flag.BoolVar(&excludeControl, "exclude-control-records", false, "Count messages by consuming each partition instead of subtracting offsets, so transaction control records (and compacted-away offsets) are not counted; reads every record and is slow on large topics")
+	flag.StringVar(&atTimestamp, "at-timestamp", "", "Add an offset_at_time column: sum over partitions of the offset at this RFC 3339 time (e.g. 2024-01-01T00:00:00Z)")
+	flag.BoolVar(&batchOffsets, "batch-offsets", false, "Fetch earliest and latest offsets with one ListOffsets request per leader broker instead of two requests per partition; partitions missing from the batch fall back to per-partition requests")
+	flag.StringVar(&countMode, "count-mode", "retained", "How messages are counted: retained is what the log holds now (latest - earliest offset), total is everything ever written (sum of latest offsets, including messages deleted by retention)")
+	flag.DurationVar(&messageWindow, "message-window", 0, "Count only messages written within this window before now (e.g. 24h) using offsets-for-timestamp; 0 counts the whole log")
+	flag.DurationVar(&rateInterval, "rate-interval", 0, "Sample high watermarks twice this far apart and report msgs_per_sec (doubles offset requests and adds the wait to the run time)")
+	flag.BoolVar(&withPercentiles, "with-percentiles", false, "Add p95_partition_messages column: 95th percentile of per-partition message counts, equal to the max for topics with fewer than 20 partitions")
+	flag.BoolVar(&activePartitions, "active-partitions", false, "Add active_partitions (partitions holding messages) and avg_msgs_per_active_partition columns")
+	flag.BoolVar(&neverWritten, "never-written", false, "Add never_written column: true when every partition's high watermark is 0, so nothing was ever produced (topics whose data expired by retention are false)")
+	flag.BoolVar(&onlyNeverWritten, "only-never-written", false, "Only report topics nothing was ever produced to, see --never-written; strong deletion candidates")
+	flag.Float64Var(&overPartitioned, "over-partitioned-threshold", 0, "Only report topics with messages but fewer than R messages per partition, candidates for partition reduction, and add a msgs_per_partition column (0 disables)")
+	flag.StringVar(&skewMetric, "skew-metric", "range", "Partition skew column to add: range (skew, max-min messages), stddev (skew_stddev) or cv (skew_cv, stddev/mean); shown with --auto-detail-skew or when set explicitly, the --auto-detail-skew threshold always uses range")
+	flag.Int64Var(&autoDetailSkew, "auto-detail-skew", 0, "Add per-partition rows for topics whose partition skew (max-min messages) exceeds N (0 disables)")
+	flag.BoolVar(&minISR, "min-isr", false, "Add replication_factor, min_isr (effective min.insync.replicas from DescribeConfig) and durable columns; durable is false when min.insync.replicas is not below the replication factor, so losing one replica stops writes with acks=all")
+	flag.BoolVar(&singleReplica, "single-replica", false, "Add single_replica column flagging topics with a partition that has only one replica")
+	flag.BoolVar(&commitAge, "commit-age", false, "Add last_commit_age column (how long ago the stalest active group last committed); reads "+consumerOffsetsTopic+", see --commit-age-lookback")
+	flag.DurationVar(&commitAgeLookback, "commit-age-lookback", 24*time.Hour, "How far back --commit-age reads "+consumerOffsetsTopic+": the scan starts at the offset found by timestamp and reads every commit since then in each coordinator partition, so the cost grows with the window and the commit rate; a group with no commit in the window is reported with the window as its age; the column is left empty when a coordinator partition could not be read to the end; 0 reads the whole log")
+	flag.BoolVar(&category, "category", false, "Add a category column: internal (--internal-prefix), changelog, repartition or business")
+	flag.StringVar(&changelogSuffixes, "changelog-suffixes", "-changelog", "Comma-separated topic name suffixes classified as changelog by --category")
+	flag.StringVar(&repartitionSuffixes, "repartition-suffixes", "-repartition", "Comma-separated topic name suffixes classified as repartition by --category")
+	flag.StringVar(&group, "group", "", "Only report topics this consumer group has committed offsets for, with a lag column for the group")
+	flag.BoolVar(&transpose, "transpose", false, "With --format csv, swap rows and columns: one line per column, one column per topic; meant for reports on a few topics")
+	flag.BoolVar(&withTimestamp, "with-timestamp", false, "Prepend a collected_at column with the RFC3339 time of collection, the same for every row of a run (cache creation time with --cache-file)")
+	flag.BoolVar(&totalLag, "total-lag", false, "Add a total_lag column: lag of all groups with active members summed per topic (each group counts once per partition)")
+	flag.BoolVar(&groupCount, "group-count", false, "Add a group_count column: number of consumer groups reading the topic, while consumers sums their members")
+	flag.BoolVar(&groupsNoCommit, "groups-no-commit", false, "Add a groups_no_commit column listing groups with active members subscribed to the topic but without committed offsets")
+	flag.BoolVar(&oldestMessageAge, "oldest-message-age", false, "Add an oldest_message_age column from the earliest record timestamp across partitions (reads one record per partition)")
+	flag.BoolVar(&totals, "totals", false, "Print a replication health summary of all selected topics to stderr at exit, including topics dropped by --over-partitioned-threshold and --only-never-written (also enabled by -v)")
+	flag.BoolVar(&replicaAssignment, "replica-assignment", false, "Add per-partition rows for every topic with replicas and isr broker lists in assignment order (first replica is the preferred leader)")
+	flag.BoolVar(&leaderEpoch, "leader-epoch", false, "Add per-partition rows for every topic with leader_epoch from the client metadata (blank before --kafka-version 2.1.0, which does not report it)")
+	flag.BoolVar(&leaderBrokerAddrs, "leader-brokers", false, "Add a leader_brokers column: distinct host:port of the brokers leading the topic's partitions, joined by ; and always quoted in CSV")
+	flag.BoolVar(&preferredLeader, "preferred-leader", false, "Add a non_preferred_leaders column counting partitions led by a replica other than the first one; per-partition rows also get preferred_leader_imbalance")
+	flag.BoolVar(&replicaLag, "replica-lag", false, "Add per-partition rows for every topic with replica_lag, the largest follower lag in messages from DescribeLogDirs (blank where brokers do not report it)")
+	flag.BoolVar(&replicationLag, "replication-lag", false, "Add a replication_lag column, the largest follower lag in messages across the topic's partitions from DescribeLogDirs (blank where brokers do not report it); cheaper than --replica-lag")
+	flag.BoolVar(&rateDetail, "rate-detail", false, "With --rate-interval, add per-partition rows with their own msgs_per_sec (all topics are sampled in one cluster-wide pass, not per topic)")
 	flag.BoolVar(&logVerbose, "v", false, "Verbose logging to stderr")
+	flag.BoolVar(&allowNoCluster, "allow-no-cluster", false, "If the Kafka client cannot be created, print an empty report (just the header) and exit 0 instead of failing")
+	flag.IntVar(&connectRetries, "connect-retries", 0, "How many times to retry Kafka client creation before giving up")
+	flag.DurationVar(&connectBackoff, "connect-retry-backoff", 2*time.Second, "Pause between client creation retries")
+	flag.IntVar(&groupOffsetRetry.Retries, "group-offset-retries", 2, "How many times to retry a consumer group offset fetch on transient coordinator errors")
+	flag.IntVar(&groupConcurrency, "group-offset-concurrency", 1, "How many consumer groups to fetch committed offsets for at the same time")
+	flag.IntVar(&groupDescribeBatch, "group-describe-batch-size", 500, "Describe consumer groups in concurrent batches of N groups; a failed batch is retried with the group offset retry settings (0 means a single request)")
+	flag.DurationVar(&groupOffsetRetry.Backoff, "group-offset-retry-backoff", 500*time.Millisecond, "Pause between consumer group offset fetch retries")
+	flag.DurationVar(&groupOffsetRetry.LoadTimeout, "coordinator-load-timeout", 30*time.Second, "How long to keep retrying group requests while the group coordinator is still loading offsets (e.g. right after a broker restart); these retries do not count against --group-offset-retries")
+	flag.IntVar(&metadataRetries, "metadata-retries", 3, "Metadata request retries")
+	flag.DurationVar(&metadataBackoff, "metadata-retry-backoff", 250*time.Millisecond, "Pause between metadata request retries")
+	flag.DurationVar(&metadataRefresh, "metadata-refresh", 10*time.Minute, "Background metadata refresh interval (0 disables)")
+	flag.BoolVar(&metadataFull, "metadata-full", true, "Fetch metadata for all cluster topics; false fetches only the topics in use")
+	flag.StringVar(&securityProtocol, "security-protocol", "", "Security protocol as in Kafka client configs: "+strings.Join(securityProtocols, ", ")+"; empty means PLAINTEXT, or SASL_PLAINTEXT with --sasl-mechanism")
+	flag.StringVar(&rackID, "rack-id", "", "Client rack sent with fetch requests, so a cluster with follower fetching (KIP-392, Kafka 2.4+ with replica.selector.class set) serves records from a replica in this rack; affects only record reads by --exclude-control-records and --oldest-message-age, offsets always come from the leader")
+	flag.StringVar(&tlsServerName, "tls-server-name", "", "Server name to verify broker certificates against (SNI), when brokers are reached through a proxy or load balancer under a different host name; requires an SSL security protocol")
+	flag.StringVar(&saslMechanism, "sasl-mechanism", "", "SASL mechanism (GSSAPI, PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, AWS_MSK_IAM), empty disables SASL")
+	flag.StringVar(&awsRegion, "aws-region", "", "AWS region of the MSK cluster for AWS_MSK_IAM (defaults to AWS_REGION or the AWS profile)")
+	flag.StringVar(&saslCreds.Username, "sasl-username", "", "SASL username for PLAIN and SCRAM mechanisms")
+	flag.StringVar(&saslCreds.Password, "sasl-password", "", "SASL password for PLAIN and SCRAM mechanisms")
+	flag.StringVar(&commandConfigPath, "command-config", "", "Kafka client properties file (as for kafka-topics.sh --command-config): security.protocol, sasl.mechanism and PLAIN/SCRAM credentials from sasl.jaas.config; explicit flags take precedence")
+	flag.StringVar(&gssapi.ServiceName, "kerberos-service-name", "kafka", "Kerberos service name of the brokers")
+	flag.StringVar(&gssapi.Realm, "kerberos-realm", "", "Kerberos realm")
+	flag.StringVar(&gssapi.Username, "kerberos-username", "", "Kerberos principal name (without realm)")
+	flag.StringVar(&gssapi.Password, "kerberos-password", "", "Kerberos password (used when no keytab is given)")
+	flag.StringVar(&gssapi.KeytabPath, "kerberos-keytab", "", "Path to Kerberos keytab")
+	flag.StringVar(&gssapi.ConfigPath, "kerberos-config", "", "Path to krb5.conf")
+	flag.StringVar(&gssapi.KDC, "kerberos-kdc", "", "KDC address host:port (used when no krb5.conf is given)")
 	flag.Parse()
+	envFallback("format", "KAFKA_REPORT_FORMAT", &format)
+	if commandConfigPath != "" {
+		cc, err := parseCommandConfig(commandConfigPath)
+		if err != nil {
+			log.Fatalf("invalid command-config: %v", err)
+		}
+		cc.apply(&securityProtocol, &saslMechanism, &saslCreds)
+	}
 
 	if !logVerbose {
 		log.SetOutput(os.Stderr)
 	}
+	if errorsAsRows {
+		reportErrors = newErrorLog(os.Stderr)
+		log.SetOutput(reportErrors)
+	}
+
+	if listFormats {
+		printFormats(os.Stdout)
+		return
+	}
+
+	brokers := splitList(brokersStr)
+	if len(brokers) == 0 {
+		log.Fatalf("invalid brokers: no broker addresses in %q", brokersStr)
+	}
+
+	expectedPartitions, err := parseExpectedPartitions(expectedPartitionsStr)
+	if err != nil {
+		log.Fatalf("invalid expected-partitions: %v", err)
+	}
+
+	assertions, err := parseAssertions(assertSpec)
+	if err != nil {
+		log.Fatalf("invalid assert: %v", err)
+	}
+
+	var atTime time.Time
+	if atTimestamp != "" {
+		atTime, err = time.Parse(time.RFC3339, atTimestamp)
+		if err != nil {
+			log.Fatalf("invalid at-timestamp: %v", err)
+		}
+	}
+
+	columns := baseColumns
+	detailRows := autoDetailSkew > 0 || rateDetail || replicaAssignment || replicaLag || leaderEpoch
+	if detailRows {
+		columns = append([]column{baseColumns[0], partitionColumn}, baseColumns[1:]...)
+	}
+	if !slices.Contains(skewMetrics, skewMetric) {
+		log.Fatalf("invalid skew-metric %q, use one of: %s", skewMetric, strings.Join(skewMetrics, ", "))
+	}
+	if autoDetailSkew > 0 || flagExplicit("skew-metric") {
+		columns = append(columns, skewColumns[skewMetric])
+	}
+	if len(expectedPartitions) > 0 {
+		columns = append(columns, expectedPartitionsColumns...)
+	}
+	if singleReplica {
+		columns = append(columns, singleReplicaColumns...)
+	}
+	if minISR {
+		columns = append(columns, minISRColumns...)
+	}
+	if commitAge {
+		columns = append(columns, commitAgeColumns...)
+	}
+	if !atTime.IsZero() {
+		columns = append(columns, offsetAtTimeColumns...)
+	}
+	if category {
+		columns = append(columns, categoryColumns...)
+	}
+	if group != "" {
+		columns = append(columns, groupLagColumns...)
+	}
+	if totalLag {
+		columns = append(columns, totalLagColumns...)
+	}
+	if groupCount {
+		columns = append(columns, groupCountColumns...)
+	}
+	if groupsNoCommit {
+		columns = append(columns, groupsNoCommitColumns...)
+	}
+	if oldestMessageAge {
+		columns = append(columns, oldestMessageAgeColumns...)
+	}
+	if rateInterval > 0 {
+		columns = append(columns, rateColumns...)
+	}
+	if replicaAssignment {
+		columns = append(columns, replicaAssignmentColumns...)
+	}
+	if preferredLeader {
+		columns = append(columns, nonPreferredLeadersColumns...)
+		if detailRows {
+			columns = append(columns, preferredLeaderImbalanceColumns...)
+		}
+	}
+	if leaderBrokerAddrs {
+		columns = append(columns, leaderBrokersColumns...)
+	}
+	if replicaLag {
+		columns = append(columns, replicaLagColumns...)
+	}
+	if replicationLag {
+		columns = append(columns, replicationLagColumns...)
+	}
+	if activePartitions {
+		columns = append(columns, activePartitionsColumns...)
+	}
+	if withPercentiles {
+		columns = append(columns, percentileColumns...)
+	}
+	if overPartitioned > 0 {
+		columns = append(columns, overPartitionedColumns...)
+	}
+	if neverWritten {
+		columns = append(columns, neverWrittenColumns...)
+	}
+	if leaderEpoch {
+		columns = append(columns, leaderEpochColumns...)
+	}
+	if topicTimeout > 0 {
+		columns = append(columns, incompleteColumns...)
+	}
+
+	outFormat, err := findFormat(format)
+	if err != nil {
+		log.Fatalf("invalid format: %v", err)
+	}
+	if errorsAsRows {
+		switch outFormat.Name {
+		case "json-object":
+			// ошибки выводятся отдельным массивом errors
+		case "csv", "json", "jsonl":
+			if transpose {
+				log.Fatalf("--errors-as-rows cannot be combined with --transpose")
+			}
+			outFormat = withErrorRows(outFormat)
+		default:
+			log.Fatalf("--errors-as-rows is not supported for %s format", outFormat.Name)
+		}
+	}
+	if transpose {
+		if outFormat.Name != "csv" {
+			log.Fatalf("--transpose requires --format csv")
+		}
+		if stream {
+			log.Fatalf("--transpose cannot be combined with --stream")
+		}
+		outFormat = transposed(outFormat)
+	}
+	if withTimestamp {
+		if outFormat.Name == "influx" {
+			log.Fatalf("--with-timestamp cannot be combined with --format influx, its records already carry a timestamp")
+		}
+		outFormat = withCollectedAt(outFormat)
+	}
+	if stream && !outFormat.Stream {
+		log.Fatalf("--stream is not supported for %s format", format)
+	}
+	if stream && rateInterval > 0 {
+		log.Fatalf("--stream cannot be combined with --rate-interval")
+	}
+	if flattenGroups && rateInterval > 0 {
+		log.Fatalf("--flatten-groups cannot be combined with --rate-interval")
+	}
+	var sortColumn *column
+	if sortBy != "" {
+		i := slices.IndexFunc(columns, func(c column) bool { return c.Name == sortBy })
+		if i < 0 {
+			log.Fatalf("invalid sort: column %q is not in the output, use one of: %s", sortBy, strings.Join(columnNames(columns), ", "))
+		}
+		sortColumn = &columns[i]
+	}
+	if sortDesc && sortBy == "" {
+		log.Fatalf("--sort-desc requires --sort")
+	}
+	if limit < 0 {
+		log.Fatalf("invalid limit: %d", limit)
+	}
+	if (sortBy != "" || limit > 0) && (stream || flattenGroups) {
+		log.Fatalf("--sort and --limit cannot be combined with --stream or --flatten-groups")
+	}
+	if collapseDepth < 0 {
+		log.Fatalf("invalid collapse-prefix-depth: %d", collapseDepth)
+	}
+	if collapseDepth > 0 {
+		for _, c := range columns {
+			if !slices.Contains([]string{"topic", "partitions", "consumers", "messages", "msgs_per_sec"}, c.Name) {
+				log.Fatalf("--collapse-prefix-depth cannot be combined with the %s column", c.Name)
+			}
+		}
+		if stream || flattenGroups || report != "topics" {
+			log.Fatalf("--collapse-prefix-depth is supported only for the topics report without --stream and --flatten-groups")
+		}
+	}
+	if !slices.Contains(countModes, countMode) {
+		log.Fatalf("invalid count-mode %q, use one of: %s", countMode, strings.Join(countModes, ", "))
+	}
+	if countMode == "total" && (messageWindow > 0 || excludeControl) {
+		log.Fatalf("--count-mode total cannot be combined with --message-window and --exclude-control-records")
+	}
+	if groupOffsetRetry.LoadTimeout < 0 {
+		log.Fatalf("invalid coordinator-load-timeout: %s", groupOffsetRetry.LoadTimeout)
+	}
+	if commitAgeLookback < 0 {
+		log.Fatalf("invalid commit-age-lookback: %s", commitAgeLookback)
+	}
+	if stable && noSort {
+		log.Fatalf("--stable cannot be combined with --no-sort")
+	}
+	if groupConcurrency < 1 {
+		log.Fatalf("invalid group-offset-concurrency: %d", groupConcurrency)
+	}
+	if groupDescribeBatch < 0 {
+		log.Fatalf("invalid group-describe-batch-size: %d", groupDescribeBatch)
+	}
+	if rateDetail && rateInterval <= 0 {
+		log.Fatalf("--rate-detail requires --rate-interval")
+	}
+	if !slices.Contains(reportModes, report) {
+		log.Fatalf("invalid report %q, use one of: %s", report, strings.Join(reportModes, ", "))
+	}
+	if printSchema {
+		if report != "topics" || flattenGroups {
+			log.Fatalf("--print-schema is supported only for the topics report without --flatten-groups")
+		}
+		if err := writeRowSchema(os.Stdout, columns, detailRows); err != nil {
+			log.Fatalf("failed to write schema: %v", err)
+		}
+		return
+	}
+	if overPartitioned < 0 {
+		log.Fatalf("invalid over-partitioned-threshold: must not be negative")
+	}
+	if overPartitioned > 0 && report != "topics" && report != "histogram" {
+		log.Fatalf("--over-partitioned-threshold is supported only for the topics and histogram reports")
+	}
+	if onlyNeverWritten && report != "topics" && report != "histogram" {
+		log.Fatalf("--only-never-written is supported only for the topics and histogram reports")
+	}
+	if reassignTemplate && report != "topics" {
+		log.Fatalf("--emit-reassignment-template is supported only for the topics report")
+	}
+	if group != "" && report != "topics" && report != "assignment" {
+		log.Fatalf("--group is supported only for the topics and assignment reports")
+	}
+	if report == "assignment" && group == "" {
+		log.Fatalf("--report assignment requires --group")
+	}
+	if report == "by-group" {
+		if !strings.HasPrefix(outFormat.Name, "json") {
+			log.Fatalf("--report by-group requires a JSON format")
+		}
+		if stream || flattenGroups || sortBy != "" || limit > 0 {
+			log.Fatalf("--report by-group cannot be combined with --stream, --flatten-groups, --sort and --limit")
+		}
+	}
+	var histogramBounds []int64
+	if report == "histogram" {
+		if stream || flattenGroups || sortBy != "" || limit > 0 {
+			log.Fatalf("--report histogram cannot be combined with --stream, --flatten-groups, --sort and --limit")
+		}
+		histogramBounds, err = parseHistogramBuckets(histogramBuckets)
+		if err != nil {
+			log.Fatalf("invalid histogram-buckets: %v", err)
+		}
+	} else if flagExplicit("histogram-buckets") {
+		log.Fatalf("--histogram-buckets requires --report histogram")
+	}
+	if configKeys != "" && report != "broker-config" {
+		log.Fatalf("--config-keys requires --report broker-config")
+	}
+	var conventionRe *regexp.Regexp
+	if report == "naming" {
+		if convention == "" {
+			log.Fatalf("--report naming requires --convention")
+		}
+		conventionRe, err = regexp.Compile(convention)
+		if err != nil {
+			log.Fatalf("invalid convention: %v", err)
+		}
+	} else if convention != "" {
+		log.Fatalf("--convention requires --report naming")
+	}
+	var baseline configBaseline
+	if report == "config-drift" {
+		if configBaselinePath == "" {
+			log.Fatalf("--report config-drift requires --config-baseline")
+		}
+		baseline, err = readConfigBaseline(configBaselinePath)
+		if err != nil {
+			log.Fatalf("invalid config-baseline: %v", err)
+		}
+	} else if configBaselinePath != "" {
+		log.Fatalf("--config-baseline requires --report config-drift")
+	}
+	if strict && report != "naming" && report != "config-drift" {
+		log.Fatalf("--strict requires --report naming or config-drift")
+	}
+	var inventory []string
+	if report == "shadow" {
+		if inventoryPath == "" {
+			log.Fatalf("--report shadow requires --inventory")
+		}
+		inventory, err = readNamesFile(inventoryPath)
+		if err != nil {
+			log.Fatalf("failed to read inventory: %v", err)
+		}
+	}
+	if outFormat.Name == "protobuf" && (report != "topics" || flattenGroups) {
+		log.Fatalf("--format protobuf is supported only for the topics report without --flatten-groups")
+	}
+	if outFormat.Name == "tree" && (report != "topics" || flattenGroups) {
+		log.Fatalf("--format tree is supported only for the topics report without --flatten-groups")
+	}
+	if stream && report != "topics" {
+		log.Fatalf("--stream is supported only for the topics report")
+	}
+	if cacheFile != "" && (report != "topics" || stream || flattenGroups) {
+		log.Fatalf("--cache-file is supported only for the topics report without --stream and --flatten-groups")
+	}
+	if len(assertions) > 0 && (report != "topics" || flattenGroups || cacheFile != "") {
+		log.Fatalf("--assert is supported only for the topics report without --flatten-groups and --cache-file")
+	}
+	if cacheMaxAge != 0 && cacheFile == "" {
+		log.Fatalf("--cache-max-age requires --cache-file")
+	}
+	if tee && outputPath == "" {
+		log.Fatalf("--tee requires --output")
+	}
+	var dest io.Writer = os.Stdout
+	var destFile io.Closer
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			log.Fatalf("failed to create output file: %v", err)
+		}
+		dest, destFile = f, f
+		if tee {
+			dest = io.MultiWriter(os.Stdout, f)
+		}
+	}
+	// log.Fatalf не выполняет defer, поэтому дальше ошибки — через fatalf
+	output := newBufferedOutput(dest, destFile, stream)
+	reportOutput = output
+	output.closeOnSignal()
+	defer func() {
+		if err := output.Close(); err != nil {
+			log.Fatalf("failed to write report: %v", err)
+		}
+	}()
+	defer runExitCleanups()
+
+	renderOpts := renderOptions{Human: human, NullString: nullString, Measurement: "kafka_topic", Brokers: brokers, CollectedAt: time.Now(), Stable: stable}
+	switch report {
+	case "histogram":
+		renderOpts.Measurement = "kafka_topic_histogram"
+	case "by-group":
+		renderOpts.RowsKey = "groups"
+	}
+	header := columnNames(columns)
+	if flattenGroups {
+		header = flattenedGroupsHeader
+		renderOpts.Measurement = "kafka_topic_group"
+	}
+	if report == "topics" && !flattenGroups {
+		renderOpts.RowsKey = "topics"
+	}
+	if outFormat.Name == "sql" {
+		if !sqlIdentifierRe.MatchString(sqlTable) {
+			fatalf("invalid table %q: use letters, digits, '_' and '.' for a schema", sqlTable)
+		}
+		renderOpts.Table = sqlTable
+		renderOpts.CreateTable = sqlCreateTable
+		if report == "topics" && !flattenGroups {
+			renderOpts.ColumnTypes = make(map[string]string, len(columns))
+			for _, c := range columns {
+				renderOpts.ColumnTypes[c.Name] = c.Type
+			}
+		}
+	} else if flagExplicit("table") || sqlCreateTable {
+		fatalf("--table and --sql-create-table require --format sql")
+	}
+	// заголовок, который увидит пользователь, — для проверки переименований
+	outputHeader := header
+	if report != "topics" {
+		outputHeader = reportHeaders[report]
+	}
+	if errorsAsRows && outFormat.Name != "json-object" {
+		outputHeader = append(slices.Clone(outputHeader), "error")
+	}
+	if withTimestamp {
+		outputHeader = append([]string{"collected_at"}, outputHeader...)
+	}
+	if jsonFieldMap != "" {
+		if !strings.HasPrefix(outFormat.Name, "json") {
+			fatalf("--json-field-map requires a JSON format")
+		}
+		renderOpts.FieldNames, err = parseFieldMap(jsonFieldMap)
+		if err != nil {
+			fatalf("invalid json-field-map: %v", err)
+		}
+		if err := checkFieldMap(outputHeader, renderOpts.FieldNames); err != nil {
+			fatalf("invalid json-field-map: %v", err)
+		}
+	}
+	if headerMap != "" {
+		if outFormat.Name != "csv" {
+			fatalf("--header-map requires --format csv")
+		}
+		renderOpts.HeaderNames, err = parseFieldMap(headerMap)
+		if err != nil {
+			fatalf("invalid header-map: %v", err)
+		}
+		if err := checkFieldMap(outputHeader, renderOpts.HeaderNames); err != nil {
+			fatalf("invalid header-map: %v", err)
+		}
+	}
+	out := outFormat.New(output, header, renderOpts)
+
+	var consumersMatch countFilter
+	if consumersFilter != "" {
+		consumersMatch, err = parseCountFilter(consumersFilter)
+		if err != nil {
+			fatalf("invalid consumers: %v", err)
+		}
+	}
+	if maxPartitions > 0 && minPartitions > maxPartitions {
+		fatalf("min-partitions (%d) is greater than max-partitions (%d)", minPartitions, maxPartitions)
+	}
 
-	brokers := strings.Split(brokersStr, ",")
+	var explicitTopics map[string]bool
+	var explicitNames []string
+	if topicsSpec != "" {
+		names, err := readTopicNames(topicsSpec, os.Stdin)
+		if err != nil {
+			fatalf("invalid topics: %v", err)
+		}
+		explicitTopics = make(map[string]bool, len(names))
+		for _, name := range names {
+			explicitTopics[name] = true
+		}
+		explicitNames = names
+	}
+	var topicPatterns []string
+	if topicsFile != "" {
+		topicPatterns, err = readTopicPatterns(topicsFile)
+		if err != nil {
+			fatalf("failed to read topics-file: %v", err)
+		}
+		if explicitTopics == nil {
+			explicitTopics = make(map[string]bool)
+		}
+	}
 
 	busRe, err := regexp.Compile(businessRegexp)
 	if err != nil {
-		log.Fatalf("invalid business-regexp: %v", err)
+		fatalf("invalid business-regexp: %v", err)
+	}
+	isBusiness := busRe.MatchString
+	if !flagExplicit("business-regexp") {
+		// регулярное выражение по умолчанию описывает только префикс "_",
+		// служебные топики отсекаются по --internal-prefix
+		isBusiness = func(name string) bool { return !isInternalTopic(name, internalPrefix) }
+	} else if flagExplicit("internal-prefix") {
+		log.Printf("WARN: --internal-prefix is ignored because --business-regexp is set")
+	}
+
+	categories := topicCategories{
+		InternalPrefix:      internalPrefix,
+		ChangelogSuffixes:   splitList(changelogSuffixes),
+		RepartitionSuffixes: splitList(repartitionSuffixes),
+	}
+
+	cache := cacheKey{
+		Brokers:           brokers,
+		BusinessRegexp:    businessRegexp,
+		InternalPrefix:    internalPrefix,
+		Group:             group,
+		TopicGrep:         topicGrep,
+		Topics:            explicitNames,
+		TopicPatterns:     topicPatterns,
+		MinPartitions:     minPartitions,
+		MaxPartitions:     maxPartitions,
+		ConsumersFilter:   consumersFilter,
+		OverPartitioned:   overPartitioned,
+		OnlyNeverWritten:  onlyNeverWritten,
+		Columns:           columnNames(columns),
+		AutoDetailSkew:    autoDetailSkew,
+		RateDetail:        rateDetail,
+		ReplicaAssignment: replicaAssignment,
+		MessageWindow:     messageWindow,
+		ExcludeControl:    excludeControl,
+		CountMode:         countMode,
+		AtTimestamp:       atTimestamp,
+		RateInterval:      rateInterval,
+
+		ExpectedPartitions: expectedPartitions,
+		Categories:         categories,
+	}
+	if cacheFile != "" {
+		rows, createdAt, ok, err := loadCache(cacheFile, cache, cacheMaxAge)
+		switch {
+		case err != nil:
+			log.Printf("WARN: cache %s: %v, collecting from the cluster", cacheFile, err)
+		case ok:
+			if logVerbose {
+				log.Printf("rendering from cache %s created at %s", cacheFile, createdAt.Format(time.RFC3339))
+			}
+			if withTimestamp {
+				renderOpts.CollectedAt = createdAt
+				out = outFormat.New(output, header, renderOpts)
+			}
+			if collapseDepth > 0 {
+				rows = collapseRows(rows, collapseDepth)
+			}
+			if err := writeOrderedRows(out, columns, rows, sortColumn, sortDesc, limit); err != nil {
+				fatalf("failed to write report: %v", err)
+			}
+			return
+		case logVerbose && !createdAt.IsZero():
+			log.Printf("cache %s is stale or built with other options, collecting from the cluster", cacheFile)
+		}
 	}
 
 	cfg := sarama.NewConfig()
 	cfg.Net.DialTimeout = 5 * time.Second
 	cfg.Net.ReadTimeout = 10 * time.Second
 	cfg.Net.WriteTimeout = 10 * time.Second
-	cfg.Metadata.Retry.Max = 3
+	cfg.Metadata.Retry.Max = metadataRetries
+	cfg.Metadata.Retry.Backoff = metadataBackoff
+	cfg.Metadata.RefreshFrequency = metadataRefresh
+	cfg.Metadata.Full = metadataFull
 	cfg.Consumer.Offsets.AutoCommit.Enable = false
+	if rateLimit < 0 {
+		fatalf("invalid rate-limit: %v", rateLimit)
+	}
+	if rateLimit > 0 {
+		requestLimiter = rate.NewLimiter(rate.Limit(rateLimit), 1)
+	}
 
 	version, err := parseKafkaVersion(kafkaVersionStr)
 	if err != nil {
-		log.Fatalf("invalid kafka-version: %v", err)
+		if !versionFallback {
+			fatalf("invalid kafka-version: %v", err)
+		}
+		log.Printf("WARN: invalid kafka-version: %v, falling back to %s", err, fallbackKafkaVersion)
+		version = fallbackKafkaVersion
 	}
 	cfg.Version = version
+	if rackID != "" {
+		if !cfg.Version.IsAtLeast(sarama.V2_4_0_0) {
+			fatalf("--rack-id requires --kafka-version 2.4.0 or later, follower fetching is not available before")
+		}
+		if !excludeControl && !oldestMessageAge {
+			log.Printf("WARN: --rack-id has no effect without --exclude-control-records or --oldest-message-age, other requests go to partition leaders")
+		}
+		cfg.RackID = rackID
+	}
 
-	client, err := sarama.NewClient(brokers, cfg)
+	if err := configureSecurityProtocol(cfg, securityProtocol, saslMechanism); err != nil {
+		fatalf("invalid security-protocol: %v", err)
+	}
+	if tlsServerName != "" {
+		if !cfg.Net.TLS.Enable {
+			fatalf("--tls-server-name requires --security-protocol SSL or SASL_SSL")
+		}
+		cfg.Net.TLS.Config.ServerName = tlsServerName
+	}
+
+	cleanupSASL, err := configureSASL(cfg, saslMechanism, saslCreds, gssapi, awsRegion)
+	if err != nil {
+		fatalf("invalid sasl config: %v", err)
+	}
+	atExit(cleanupSASL)
+
+	client, err := newClient(brokers, cfg, connectRetries, connectBackoff, logVerbose)
+	if err != nil && allowNoCluster {
+		log.Printf("WARN: failed to create Kafka client: %v, writing an empty report", err)
+		if report != "topics" {
+			out = outFormat.New(output, reportHeaders[report], renderOpts)
+		}
+		if err := writeEmptyReport(out); err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+		return
+	}
 	if err != nil {
-		log.Fatalf("failed to create Kafka client: %v", err)
+		fatalf("failed to create Kafka client: %v", err)
 	}
-	defer client.Close()
+	atExit(func() { client.Close() })
+
+	// при Metadata.Full=false без этого клиент вообще не знает контроллер,
+	// а он нужен admin-клиенту
+	if err := refreshMetadata(client, metadataBackoff, logVerbose); err != nil {
+		fatalf("failed to fetch metadata: %v", err)
+	}
+	checkBrokerVersions(client, cfg.Version, logVerbose)
 
 	admin, err := sarama.NewClusterAdminFromClient(client)
 	if err != nil {
-		log.Fatalf("failed to create cluster admin: %v", err)
+		fatalf("failed to create cluster admin: %v", err)
 	}
 	defer admin.Close()
 
 	// ===== TOPICS =====
+	throttle()
 	topicsMeta, err := admin.ListTopics()
 	if err != nil {
-		log.Fatalf("failed to list topics: %v", err)
+		fatalf("failed to list topics: %v", err)
+	}
+
+	var groupOffsets map[string]groupConsumption
+	if group != "" {
+		groupOffsets, err = groupTopicOffsets(admin, group, groupOffsetRetry)
+		if err != nil {
+			fatalf("failed to fetch offsets of group %s: %v", group, err)
+		}
+		if len(groupOffsets) == 0 {
+			log.Printf("WARN: group %s has no committed offsets", group)
+		}
+	}
+
+	for name := range explicitTopics {
+		if _, ok := topicsMeta[name]; !ok {
+			log.Printf("WARN: topic %s does not exist", name)
+		}
 	}
 
 	var topics []string
 	for name := range topicsMeta {
-		if !busRe.MatchString(name) {
+		if explicitTopics != nil && !explicitTopics[name] && !matchAny(topicPatterns, name) {
+			continue
+		}
+		if !isBusiness(name) {
 			continue
 		}
+		if groupOffsets != nil {
+			if _, ok := groupOffsets[name]; !ok {
+				continue
+			}
+		}
 		if topicGrep != "" && !strings.Contains(name, topicGrep) {
 			continue
 		}
+		parts := int(topicsMeta[name].NumPartitions)
+		if minPartitions > 0 && parts < minPartitions {
+			continue
+		}
+		if maxPartitions > 0 && parts > maxPartitions {
+			continue
+		}
 		topics = append(topics, name)
 	}
-	sort.Strings(topics)
+	if !noSort {
+		sort.Strings(topics)
+	}
+
+	if reassignTemplate {
+		if err := writeReassignmentTemplate(output, client, topics, topicsMeta); err != nil {
+			fatalf("failed to write reassignment template: %v", err)
+		}
+		return
+	}
+
+	// ===== ОТДЕЛЬНЫЕ ОТЧЁТЫ =====
+	switch report {
+	case "acls":
+		renderOpts.Measurement = "kafka_topic_acl"
+		out := outFormat.New(output, aclReportHeader, renderOpts)
+		if err := writeACLReport(admin, cfg, topics, out); err != nil {
+			fatalf("failed to build ACL report: %v", err)
+		}
+		return
+	case "quotas":
+		renderOpts.Measurement = "kafka_client_quota"
+		out := outFormat.New(output, quotaReportHeader, renderOpts)
+		if err := writeQuotaReport(admin, cfg, out); err != nil {
+			fatalf("failed to build quota report: %v", err)
+		}
+		return
+	case "broker-config":
+		renderOpts.Measurement = "kafka_broker_config"
+		out := outFormat.New(output, brokerConfigReportHeader, renderOpts)
+		if err := writeBrokerConfigReport(admin, client, splitList(configKeys), out); err != nil {
+			fatalf("failed to build broker config report: %v", err)
+		}
+		return
+	case "naming":
+		renderOpts.Measurement = "kafka_topic_naming"
+		out := outFormat.New(output, namingReportHeader, renderOpts)
+		violations, err := writeNamingReport(topics, conventionRe, out)
+		if err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+		if violations > 0 && strict {
+			fatalf("%d topics violate the naming convention", violations)
+		}
+		return
+	case "config-drift":
+		renderOpts.Measurement = "kafka_topic_config_drift"
+		out := outFormat.New(output, configDriftReportHeader, renderOpts)
+		drift, err := writeConfigDriftReport(admin, topics, baseline, out)
+		if err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+		if drift > 0 && strict {
+			fatalf("%d topic configs drift from the baseline", drift)
+		}
+		return
+	case "orphan-offsets":
+		renderOpts.Measurement = "kafka_orphan_offsets"
+		out := outFormat.New(output, orphanOffsetsReportHeader, renderOpts)
+		if err := writeOrphanOffsetsReport(admin, topicsMeta, groupOffsetRetry, out); err != nil {
+			fatalf("failed to build orphan offsets report: %v", err)
+		}
+		return
+	case "assignment":
+		renderOpts.Measurement = "kafka_group_assignment"
+		out := outFormat.New(output, assignmentReportHeader, renderOpts)
+		if err := writeAssignmentReport(admin, group, topics, out); err != nil {
+			fatalf("failed to build assignment report: %v", err)
+		}
+		return
+	case "reassignments":
+		renderOpts.Measurement = "kafka_partition_reassignment"
+		out := outFormat.New(output, reassignmentsReportHeader, renderOpts)
+		if err := writeReassignmentsReport(admin, client, cfg, topics, out); err != nil {
+			fatalf("failed to build reassignments report: %v", err)
+		}
+		return
+	case "shadow":
+		renderOpts.Measurement = "kafka_topic_shadow"
+		out := outFormat.New(output, shadowReportHeader, renderOpts)
+		if err := writeShadowReport(topics, topicsMeta, inventory, out); err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+		return
+	}
 
 	// Если топиков нет — просто заголовок
-	fmt.Println("topic,partitions,consumers,messages")
 	if len(topics) == 0 {
+		switch report {
+		case "histogram":
+			out = outFormat.New(output, histogramReportHeader, renderOpts)
+			if err := writeHistogramReport(nil, histogramBounds, out); err != nil {
+				fatalf("failed to write report: %v", err)
+			}
+			return
+		case "by-group":
+			if err := writeEmptyReport(outFormat.New(output, byGroupReportHeader, renderOpts)); err != nil {
+				fatalf("failed to write report: %v", err)
+			}
+			return
+		}
+		if err := writeTopicRows(out, columns, nil); err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+		checkAssertions(assertions, nil)
 		return
 	}
 
@@ -98,136 +1001,450 @@ func main() {
 		log.Printf("found %d business topics", len(topics))
 	}
 
-	// ===== TOPIC OFFSETS (для messages) =====
-	topicStatsMap := make(map[string]topicStats)
-
+	// ===== CONSUMER GROUPS → сколько консьюмеров на топик =====
+	// считаем до оффсетов, чтобы в режиме --stream строку топика можно было
+	// вывести сразу после его обработки
+	topicSet := make(map[string]bool, len(topics))
 	for _, t := range topics {
-		detail := topicsMeta[t]
-		var parts int32 = detail.NumPartitions
-		if parts <= 0 {
-			partitions, err := client.Partitions(t)
+		topicSet[t] = true
+	}
+	groupsByTopic, noCommitByTopic := collectGroupConsumption(admin, topicSet, !noSort, groupDescribeBatch, groupConcurrency, groupOffsetRetry)
+	if consumersMatch != nil {
+		topics = slices.DeleteFunc(topics, func(t string) bool {
+			if consumersMatch(consumerCount(groupsByTopic[t])) {
+				return false
+			}
+			delete(topicSet, t)
+			return true
+		})
+	}
+
+	var commitTimes commitScan
+	var commitsSince time.Time
+	if commitAge && commitAgeLookback > 0 {
+		commitsSince = time.Now().Add(-commitAgeLookback)
+	}
+	if commitAge {
+		readers := make(map[string]bool)
+		for _, groups := range groupsByTopic {
+			for _, g := range groups {
+				readers[g.Group] = true
+			}
+		}
+		if len(readers) > 0 {
+			commitTimes, err = lastCommitTimes(client, readers, commitsSince)
 			if err != nil {
-				log.Printf("WARN: failed to get partitions for topic %s: %v", t, err)
-				continue
+				log.Printf("WARN: failed to read commit times from %s: %v", consumerOffsetsTopic, err)
 			}
-			parts = int32(len(partitions))
 		}
+	}
+
+	var lagsByTopic map[string]map[int32]int64
+	if replicaLag || replicationLag {
+		lagsByTopic = replicaLags(admin, client, topicSet)
+	}
+
+	var consumer sarama.Consumer
+	if oldestMessageAge || excludeControl {
+		consumer, err = sarama.NewConsumerFromClient(client)
+		if err != nil {
+			fatalf("failed to create consumer: %v", err)
+		}
+		defer consumer.Close()
+	}
 
-		var earliestSum, latestSum int64
+	// ===== TOPIC OFFSETS (для messages) + ВЫВОД =====
+	// строки, которые не нужно досчитывать после обхода, пишем сразу
+	direct := stream || flattenGroups
+	if direct {
+		if err := out.Begin(); err != nil {
+			fatalf("failed to write report: %v", err)
+		}
+	}
 
-		for p := int32(0); p < parts; p++ {
-			earliest, err := client.GetOffset(t, p, sarama.OffsetOldest)
-			if err != nil {
-				log.Printf("WARN: GetOffset(Oldest) topic=%s partition=%d: %v", t, p, err)
-				continue
+	offsetOpts := offsetOptions{AtTimestamp: atTime, TopicTimeout: topicTimeout, Total: countMode == "total"}
+	if logVerbose {
+		offsetOpts.Errors = &brokerErrors{}
+	}
+	if messageWindow > 0 {
+		offsetOpts.WindowStart = time.Now().Add(-messageWindow)
+	}
+	if excludeControl {
+		offsetOpts.Consumer = consumer
+	}
+	if batchOffsets {
+		offsetOpts.Batch = fetchOffsetBatch(client, topics)
+	}
+
+	rows := make([]Row, 0, len(topics))
+	assertRows := make(map[string]Row)
+	statsByTopic := make(map[string]topicStats, len(topics))
+	var underReplicatedTopics, offlinePartitions int
+	for _, t := range topics {
+		s := collectTopicStatsWithin(client, t, topicsMeta[t], offsetOpts)
+		// сводка --totals — по всем выбранным топикам, до фильтров строк
+		if totals || logVerbose {
+			urp, offline := partitionHealth(client, t, s.Partitions)
+			if urp > 0 {
+				underReplicatedTopics++
 			}
-			latest, err := client.GetOffset(t, p, sarama.OffsetNewest)
-			if err != nil {
-				log.Printf("WARN: GetOffset(Newest) topic=%s partition=%d: %v", t, p, err)
-				continue
+			offlinePartitions += int(offline)
+		}
+		if overPartitioned > 0 && !s.OverPartitioned(overPartitioned) {
+			continue
+		}
+		never, neverKnown := s.NeverWritten()
+		if onlyNeverWritten && !never {
+			continue
+		}
+		statsByTopic[t] = s
+		if flattenGroups {
+			for _, g := range groupsByTopic[t] {
+				if err := out.WriteRow([]any{t, g.Group, g.Members, groupLag(g, s.Latest)}); err != nil {
+					fatalf("failed to write report: %v", err)
+				}
 			}
-			if earliest < 0 {
-				earliest = 0
+			continue
+		}
+		row := Row{
+			Topic:                t,
+			Partitions:           s.Partitions,
+			Consumers:            consumerCount(groupsByTopic[t]), // 0, если никто не читает
+			Messages:             s.Messages,
+			ExpectedPartitions:   expectedPartitions[t],
+			Skew:                 s.Skew(),
+			SkewStddev:           s.SkewStddev(),
+			SkewCV:               s.SkewCV(),
+			P95PartitionMessages: s.P95PartitionMessages(),
+			ActivePartitions:     s.ActivePartitions(),
+			OffsetAtTime:         s.OffsetAtTime,
+			Incomplete:           s.Incomplete,
+		}
+		if neverWritten && neverKnown {
+			row.NeverWritten = &never
+		}
+		if commitAge {
+			row.LastCommitAge = stalestCommitAge(t, groupsByTopic[t], commitTimes, commitsSince)
+		}
+		if singleReplica || minISR || needsMinReplicas(assertions) {
+			row.MinReplicas = minReplicas(client, t, s.Partitions, topicsMeta[t])
+		}
+		if minISR {
+			row.MinISR = topicMinISR(admin, t)
+		}
+		if category {
+			row.Category = categories.classify(t)
+		}
+		if preferredLeader {
+			row.NonPreferredLeaders = nonPreferredLeaders(client, t, s.Partitions)
+		}
+		if leaderBrokerAddrs {
+			row.LeaderBrokers = leaderBrokers(client, t, s.Partitions)
+		}
+		if group != "" {
+			row.GroupLag = groupLag(groupOffsets[t], s.Latest)
+		}
+		if replicationLag {
+			row.ReplicationLag = topicReplicationLag(lagsByTopic[t])
+		}
+		if totalLag {
+			row.TotalLag = topicLag(groupsByTopic[t], s.Latest)
+		}
+		if groupCount {
+			row.GroupCount = int32(len(groupsByTopic[t]))
+		}
+		if groupsNoCommit {
+			row.GroupsNoCommit = noCommitByTopic[t]
+		}
+		if oldestMessageAge {
+			row.OldestMessageAge = -1
+			if oldest, ok := oldestMessageTime(client, consumer, t, s.Partitions); ok {
+				row.OldestMessageAge = time.Since(oldest)
+			}
+		}
+		if len(assertions) > 0 {
+			assertRows[t] = row
+		}
+		topicRows := []Row{row}
+		if rateDetail || replicaAssignment || replicaLag || leaderEpoch || (autoDetailSkew > 0 && row.Skew > autoDetailSkew) {
+			topicRows = append(topicRows, partitionRows(t, s)...)
+		}
+		if replicaAssignment {
+			for i := range topicRows[1:] {
+				r := &topicRows[i+1]
+				r.Replicas, r.ISR = partitionReplicas(client, t, r.Partition)
+			}
+		}
+		if preferredLeader {
+			for i := range topicRows[1:] {
+				r := &topicRows[i+1]
+				if notPreferred, ok := leaderNotPreferred(client, t, r.Partition); ok {
+					r.LeaderNotPreferred = &notPreferred
+				}
+			}
+		}
+		if replicaLag {
+			for i := range topicRows[1:] {
+				r := &topicRows[i+1]
+				lag, ok := lagsByTopic[t][r.Partition]
+				if !ok {
+					lag = -1
+				}
+				r.ReplicaLag = lag
+			}
+		}
+		if leaderEpoch {
+			for i := range topicRows[1:] {
+				r := &topicRows[i+1]
+				r.LeaderEpoch = partitionLeaderEpoch(client, cfg.Version, t, r.Partition)
 			}
-			if latest < 0 {
-				latest = 0
+		}
+		if direct {
+			for _, r := range topicRows {
+				if err := out.WriteRow(rowValues(columns, r)); err != nil {
+					fatalf("failed to write report: %v", err)
+				}
 			}
-			earliestSum += earliest
-			latestSum += latest
+			continue
 		}
+		rows = append(rows, topicRows...)
+	}
 
-		messages := latestSum - earliestSum
-		if messages < 0 {
-			messages = latestSum
+	// ===== RATE: второй снимок high watermark =====
+	if rateInterval > 0 && len(rows) > 0 {
+		if wait := time.Until(statsByTopic[rows[0].Topic].SampledAt.Add(rateInterval)); wait > 0 {
+			if logVerbose {
+				log.Printf("waiting %s before the second offset sample", wait.Round(time.Millisecond))
+			}
+			time.Sleep(wait)
+		}
+		// строки партиций идут сразу за строкой своего топика
+		var partitionRates map[int32]float64
+		for i := range rows {
+			if rows[i].Detail {
+				rows[i].MsgsPerSec = partitionRates[rows[i].Partition]
+				continue
+			}
+			rows[i].MsgsPerSec, partitionRates = sampleRate(client, rows[i].Topic, statsByTopic[rows[i].Topic])
 		}
+	}
 
-		topicStatsMap[t] = topicStats{
-			Partitions: parts,
-			Messages:   messages,
+	if cacheFile != "" {
+		if err := saveCache(cacheFile, cache, rows); err != nil {
+			log.Printf("WARN: failed to write cache %s: %v", cacheFile, err)
 		}
 	}
 
-	// ===== CONSUMER GROUPS → сколько консьюмеров на топик =====
-	// Шаг 1: получаем список групп
-	groupsMap, err := admin.ListConsumerGroups()
+	if report == "histogram" {
+		// гистограмма строится по собранным строкам топиков
+		err = writeHistogramReport(rows, histogramBounds, outFormat.New(output, histogramReportHeader, renderOpts))
+	} else if report == "by-group" {
+		err = writeByGroupReport(topics, groupsByTopic, statsByTopic, outFormat.New(output, byGroupReportHeader, renderOpts))
+	} else if direct {
+		err = out.End()
+	} else {
+		if collapseDepth > 0 {
+			rows = collapseRows(rows, collapseDepth)
+		}
+		err = writeOrderedRows(out, columns, rows, sortColumn, sortDesc, limit)
+	}
 	if err != nil {
-		log.Printf("WARN: failed to list consumer groups: %v", err)
+		fatalf("failed to write report: %v", err)
+	}
+
+	if totals || logVerbose {
+		log.Printf("%d topics under-replicated, %d partitions offline", underReplicatedTopics, offlinePartitions)
 	}
+	if offsetOpts.Errors != nil {
+		offsetOpts.Errors.logSummary()
+	}
+	checkAssertions(assertions, assertRows)
+}
 
-	var groupIDs []string
-	for g := range groupsMap {
-		groupIDs = append(groupIDs, g)
+// fatalf — log.Fatalf, который сначала сбрасывает буфер вывода отчёта:
+// иначе строки, сформированные до ошибки, потерялись бы.
+func fatalf(format string, args ...any) {
+	reportErrors.release()
+	if err := reportOutput.Close(); err != nil {
+		log.Printf("WARN: failed to flush output: %v", err)
 	}
-	sort.Strings(groupIDs)
+	runExitCleanups()
+	log.Fatalf(format, args...)
+}
 
-	// Шаг 2: считаем количество активных консьюмеров в группе
-	groupConsumers := make(map[string]int64)
-	if len(groupIDs) > 0 {
-		desc, err := admin.DescribeConsumerGroups(groupIDs)
-		if err != nil {
-			log.Printf("WARN: DescribeConsumerGroups: %v", err)
+// exitCleanups — освобождение ресурсов, которое должно выполниться при любом
+// выходе: fatalf и выход по сигналу завершают процесс через os.Exit, минуя
+// defer в main (временный krb5.conf остался бы на диске).
+var exitCleanups struct {
+	mu    sync.Mutex
+	funcs []func()
+}
+
+// atExit регистрирует f в exitCleanups.
+func atExit(f func()) {
+	exitCleanups.mu.Lock()
+	defer exitCleanups.mu.Unlock()
+	exitCleanups.funcs = append(exitCleanups.funcs, f)
+}
+
+// runExitCleanups выполняет зарегистрированные функции в обратном порядке,
+// каждую не больше одного раза.
+func runExitCleanups() {
+	exitCleanups.mu.Lock()
+	funcs := exitCleanups.funcs
+	exitCleanups.funcs = nil
+	exitCleanups.mu.Unlock()
+	for i := len(funcs) - 1; i >= 0; i-- {
+		funcs[i]()
+	}
+}
+
+// writeEmptyReport выводит отчёт без строк: заголовок CSV, пустой массив JSON.
+func writeEmptyReport(out rowWriter) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	return out.End()
+}
+
+// envFallback подставляет значение переменной окружения env, если флаг name
+// не задан явно в командной строке. Приоритет: флаг > переменная > умолчание.
+func envFallback(name, env string, target *string) {
+	if v := strings.TrimSpace(os.Getenv(env)); !flagExplicit(name) && v != "" {
+		*target = v
+	}
+}
+
+// flagExplicit сообщает, задан ли флаг name в командной строке.
+func flagExplicit(name string) bool {
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			explicit = true
+		}
+	})
+	return explicit
+}
+
+// refreshMetadata обновляет метаданные перед построением отчёта, повторяя
+// попытку один раз: во время выборов контроллера часть вызовов иначе молча
+// возвращает неполные данные.
+func refreshMetadata(client sarama.Client, backoff time.Duration, verbose bool) error {
+	err := client.RefreshMetadata()
+	if err != nil {
+		log.Printf("WARN: metadata refresh failed: %v, retrying once", err)
+		time.Sleep(backoff)
+		err = client.RefreshMetadata()
+	}
+	if err != nil {
+		return err
+	}
+
+	if verbose {
+		for _, b := range client.Brokers() {
+			connected, _ := b.Connected()
+			log.Printf("broker %d (%s): connected=%t", b.ID(), b.Addr(), connected)
+		}
+		if controller, err := client.Controller(); err != nil {
+			log.Printf("WARN: controller is not available: %v", err)
 		} else {
-			for _, d := range desc {
-				// активные consumers = кол-во членов
-				groupConsumers[d.GroupId] = int64(len(d.Members))
-			}
+			log.Printf("controller: broker %d (%s)", controller.ID(), controller.Addr())
 		}
 	}
+	return nil
+}
 
-	// Шаг 3: для каждой группы смотрим, какие топики она реально читает
-	// (есть коммиты offset >= 0 по хотя бы одной партиции)
-	topicConsumers := make(map[string]int64)
+// apiKeyFetch — номер запроса Fetch в протоколе Kafka.
+const apiKeyFetch = 1
 
-	for _, g := range groupIDs {
-		consCount := groupConsumers[g]
-		if consCount == 0 {
-			// у группы нет активных consumer'ов — как в UI эти группы обычно не интересуют
-			continue
+// fetchVersionReleases — первая версия Kafka, в которой появилась данная
+// версия запроса Fetch, по возрастанию. ApiVersions сообщает версии
+// запросов, но не версию брокера, а Fetch меняется почти в каждом релизе,
+// поэтому по его максимальной версии брокер определяется с точностью до
+// диапазона релизов.
+var fetchVersionReleases = []struct {
+	Fetch   int16
+	Version sarama.KafkaVersion
+}{
+	{2, sarama.V0_10_0_0},
+	{3, sarama.V0_10_1_0},
+	{4, sarama.V0_11_0_0},
+	{6, sarama.V1_0_0_0},
+	{7, sarama.V1_1_0_0},
+	{8, sarama.V2_0_0_0},
+	{10, sarama.V2_1_0_0},
+	{11, sarama.V2_3_0_0},
+	{12, sarama.V2_7_0_0},
+	{13, sarama.V3_1_0_0},
+	{15, sarama.V3_5_0_0},
+	{16, sarama.V3_7_0_0},
+}
+
+// brokerVersionRange оценивает версию брокера по максимальной версии Fetch:
+// брокер не старше from и младше before (before нулевая, если верхней
+// границы нет). ok == false для брокеров старше 0.10.0.
+func brokerVersionRange(maxFetch int16) (from, before sarama.KafkaVersion, ok bool) {
+	for i, r := range fetchVersionReleases {
+		if maxFetch < r.Fetch {
+			break
+		}
+		from, ok = r.Version, true
+		if i+1 < len(fetchVersionReleases) {
+			before = fetchVersionReleases[i+1].Version
+		} else {
+			before = sarama.KafkaVersion{}
 		}
+	}
+	return from, before, ok
+}
 
-		offsetsResp, err := admin.ListConsumerGroupOffsets(g, nil)
+// checkBrokerVersions опрашивает брокеры запросом ApiVersions и предупреждает,
+// если --kafka-version новее, чем может быть брокер: клиент тогда шлёт
+// запросы версий, которых брокер не знает, и часть вызовов отказывает без
+// внятной ошибки. С verbose выводит оценку версии каждого брокера.
+func checkBrokerVersions(client sarama.Client, configured sarama.KafkaVersion, verbose bool) {
+	for _, b := range client.Brokers() {
+		_ = b.Open(client.Config()) // ErrAlreadyConnected не мешает
+		throttle()
+		resp, err := b.ApiVersions(&sarama.ApiVersionsRequest{})
+		if err == nil && resp.ErrorCode != int16(sarama.ErrNoError) {
+			err = sarama.KError(resp.ErrorCode)
+		}
 		if err != nil {
-			log.Printf("WARN: ListConsumerGroupOffsets(group=%s): %v", g, err)
+			log.Printf("WARN: ApiVersions(broker=%d): %v", b.ID(), err)
 			continue
 		}
-
-		for topic, partMap := range offsetsResp.Blocks {
-			// нас интересуют только наши business-топики
-			if _, ok := topicStatsMap[topic]; !ok {
-				continue
-			}
-			hasOffsets := false
-			for _, block := range partMap {
-				if block == nil {
-					continue
-				}
-				if block.Offset >= 0 {
-					hasOffsets = true
-					break
-				}
+		maxFetch := int16(-1)
+		for _, k := range resp.ApiKeys {
+			if k.ApiKey == apiKeyFetch {
+				maxFetch = k.MaxVersion
 			}
-			if !hasOffsets {
-				continue
-			}
-			// эта группа реально читает этот топик → добавляем активных consumer'ов
-			topicConsumers[topic] += consCount
 		}
-	}
-
-	// ===== ВЫВОД =====
-	for _, t := range topics {
-		s := topicStatsMap[t]
-		cons := topicConsumers[t] // по умолчанию 0, если никто не читает
-
-		fmt.Printf("%s,%d,%d,%d\n",
-			t,
-			s.Partitions,
-			cons,
-			s.Messages,
-		)
+		from, before, ok := brokerVersionRange(maxFetch)
+		if !ok {
+			log.Printf("WARN: broker %d (%s) reports Fetch v%d, version is unknown", b.ID(), b.Addr(), maxFetch)
+			continue
+		}
+		versions := ">= " + from.String()
+		if before != (sarama.KafkaVersion{}) {
+			versions += ", < " + before.String()
+		}
+		if verbose {
+			log.Printf("broker %d (%s): Kafka %s (Fetch v%d)", b.ID(), b.Addr(), versions, maxFetch)
+		}
+		if before != (sarama.KafkaVersion{}) && configured.IsAtLeast(before) {
+			log.Printf("WARN: --kafka-version %s is newer than broker %d (%s) supports (Kafka %s), some requests may fail; try --kafka-version %s",
+				configured, b.ID(), b.Addr(), versions, from)
+		}
 	}
 }
 
+// fallbackKafkaVersion используется вместо неподдерживаемой --kafka-version
+// при --version-fallback.
+var fallbackKafkaVersion = sarama.V2_7_0_0
+
+// parseKafkaVersion возвращает нулевую версию вместе с ошибкой: что делать
+// с неподдерживаемой версией, решает вызывающий код.
 func parseKafkaVersion(v string) (sarama.KafkaVersion, error) {
 	switch v {
 	case "2.0.0":
@@ -259,6 +1476,41 @@ func parseKafkaVersion(v string) (sarama.KafkaVersion, error) {
 	case "3.4.0":
 		return sarama.V3_4_0_0, nil
 	default:
-		return sarama.V2_7_0_0, fmt.Errorf("unsupported version %q, use one of: 2.0.0..3.4.0", v)
+		return sarama.KafkaVersion{}, fmt.Errorf("unsupported version %q, use one of: 2.0.0..3.4.0", v)
+	}
+}
+
+// newClient создаёт клиента Kafka, повторяя попытку до retries раз:
+// при старте в оркестраторе брокеры могут быть ещё недоступны.
+func newClient(brokers []string, cfg *sarama.Config, retries int, backoff time.Duration, verbose bool) (sarama.Client, error) {
+	client, err := sarama.NewClient(brokers, cfg)
+	for attempt := 1; err != nil && attempt <= retries; attempt++ {
+		if verbose {
+			log.Printf("connect attempt %d/%d failed: %v, retrying in %s", attempt, retries, err, backoff)
+		}
+		time.Sleep(backoff)
+		client, err = sarama.NewClient(brokers, cfg)
+	}
+	return client, err
+}
+
+// parseExpectedPartitions разбирает список вида "topic=N,topic2=M".
+func parseExpectedPartitions(s string) (map[string]int32, error) {
+	expected := make(map[string]int32)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		topic, countStr, ok := strings.Cut(item, "=")
+		if !ok || topic == "" {
+			return nil, fmt.Errorf("bad entry %q, expected topic=N", item)
+		}
+		count, err := strconv.ParseInt(countStr, 10, 32)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("bad partition count in %q", item)
+		}
+		expected[topic] = int32(count)
 	}
+	return expected, nil
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestMSKTokenProvider(t *testing.T) {
+	p := &mskTokenProvider{
+		region: "eu-west-1",
+		creds:  credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "session-token"),
+	}
+	token, err := p.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token.Token)
+	if err != nil {
+		t.Fatalf("token is not base64url without padding: %v", err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "https" || u.Host != "kafka.eu-west-1.amazonaws.com" || u.Path != "/" {
+		t.Errorf("token URL = %s, want https://kafka.eu-west-1.amazonaws.com/", u)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"Action":               "kafka-cluster:Connect",
+		"X-Amz-Algorithm":      "AWS4-HMAC-SHA256",
+		"X-Amz-Expires":        "900",
+		"X-Amz-Security-Token": "session-token",
+		"X-Amz-SignedHeaders":  "host",
+	}
+	for k, v := range want {
+		if got := q.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if cred := q.Get("X-Amz-Credential"); !strings.HasPrefix(cred, "AKIDEXAMPLE/") || !strings.HasSuffix(cred, "/eu-west-1/kafka-cluster/aws4_request") {
+		t.Errorf("X-Amz-Credential = %q", cred)
+	}
+	for _, k := range []string{"X-Amz-Date", "X-Amz-Signature", "User-Agent"} {
+		if q.Get(k) == "" {
+			t.Errorf("token has no %s", k)
+		}
+	}
+}
+
+func TestMSKTokenProviderNoCredentials(t *testing.T) {
+	p := &mskTokenProvider{region: "eu-west-1", creds: aws.AnonymousCredentials{}}
+	if _, err := p.Token(); err == nil {
+		t.Error("Token() with anonymous credentials succeeded")
+	}
+}
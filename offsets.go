@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// offsetBatch — earliest и latest оффсеты партиций, полученные заранее одним
+// запросом ListOffsets на брокер-лидер вместо запроса на каждую партицию
+// (--batch-offsets). Партиции, которых в пачке нет, опрашиваются по одной.
+type offsetBatch struct {
+	// At — момент запроса, он же момент снимка high watermark
+	At      time.Time
+	Offsets map[int64]map[topicPartition]int64 // ключ — OffsetOldest/OffsetNewest
+}
+
+// offsetRequestVersion — версия ListOffsets, как её выбирает client.GetOffset.
+func offsetRequestVersion(v sarama.KafkaVersion) int16 {
+	switch {
+	case v.IsAtLeast(sarama.V2_1_0_0):
+		return 4
+	case v.IsAtLeast(sarama.V2_0_0_0):
+		return 3
+	case v.IsAtLeast(sarama.V0_11_0_0):
+		return 2
+	case v.IsAtLeast(sarama.V0_10_1_0):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// fetchOffsetBatch запрашивает earliest и latest оффсеты всех партиций
+// топиков, группируя партиции по лидеру: по два запроса на брокер. Ошибка
+// брокера или партиции не прерывает сбор — такие партиции просто не
+// попадают в пачку.
+func fetchOffsetBatch(client sarama.Client, topics []string) *offsetBatch {
+	byLeader := make(map[*sarama.Broker][]topicPartition)
+	for _, t := range topics {
+		partitions, err := client.Partitions(t)
+		if err != nil {
+			continue
+		}
+		for _, p := range partitions {
+			leader, err := client.Leader(t, p)
+			if err != nil {
+				continue
+			}
+			byLeader[leader] = append(byLeader[leader], topicPartition{t, p})
+		}
+	}
+
+	batch := &offsetBatch{At: time.Now(), Offsets: make(map[int64]map[topicPartition]int64)}
+	version := offsetRequestVersion(client.Config().Version)
+	for _, at := range []int64{sarama.OffsetOldest, sarama.OffsetNewest} {
+		offsets := make(map[topicPartition]int64)
+		for broker, partitions := range byLeader {
+			req := &sarama.OffsetRequest{Version: version}
+			for _, tp := range partitions {
+				req.AddBlock(tp.Topic, tp.Partition, at, 1)
+			}
+			throttle()
+			resp, err := broker.GetAvailableOffsets(req)
+			if err != nil {
+				log.Printf("WARN: batched ListOffsets to broker %d: %v, falling back to per-partition requests", broker.ID(), err)
+				continue
+			}
+			for _, tp := range partitions {
+				block := resp.GetBlock(tp.Topic, tp.Partition)
+				if block == nil || !errors.Is(block.Err, sarama.ErrNoError) || len(block.Offsets) != 1 {
+					continue
+				}
+				offsets[tp] = block.Offsets[0]
+			}
+		}
+		batch.Offsets[at] = offsets
+	}
+	return batch
+}
+
+// offset возвращает оффсет партиции из пачки, а если его там нет — из
+// client.GetOffset. nil-получатель допустим.
+func (b *offsetBatch) offset(client sarama.Client, t string, p int32, at int64) (int64, error) {
+	if b != nil {
+		if off, ok := b.Offsets[at][topicPartition{t, p}]; ok {
+			return off, nil
+		}
+	}
+	throttle()
+	return client.GetOffset(t, p, at)
+}
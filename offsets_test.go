@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// newOffsetsCluster поднимает sarama.MockBroker с topics топиками по
+// partitions партиций: earliest = 10*p, latest = 10*p + 5.
+func newOffsetsCluster(tb testing.TB, topics, partitions int, latency time.Duration) (sarama.Client, *sarama.MockBroker, []string) {
+	tb.Helper()
+	broker := sarama.NewMockBroker(tb, 1)
+	broker.SetLatency(latency)
+	tb.Cleanup(broker.Close)
+
+	metadata := sarama.NewMockMetadataResponse(tb).SetBroker(broker.Addr(), broker.BrokerID())
+	offsets := sarama.NewMockOffsetResponse(tb)
+	names := make([]string, topics)
+	for i := range names {
+		names[i] = fmt.Sprintf("topic-%03d", i)
+		for p := range int32(partitions) {
+			metadata.SetLeader(names[i], p, broker.BrokerID())
+			offsets.SetOffset(names[i], p, sarama.OffsetOldest, 10*int64(p))
+			offsets.SetOffset(names[i], p, sarama.OffsetNewest, 10*int64(p)+5)
+		}
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"OffsetRequest":   offsets,
+	})
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_1_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { client.Close() })
+	return client, broker, names
+}
+
+func countOffsetRequests(broker *sarama.MockBroker) int {
+	n := 0
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.OffsetRequest); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFetchOffsetBatch(t *testing.T) {
+	client, broker, topics := newOffsetsCluster(t, 3, 4, 0)
+	batch := fetchOffsetBatch(client, topics)
+
+	for _, topic := range topics {
+		for p := range int32(4) {
+			for at, want := range map[int64]int64{sarama.OffsetOldest: 10 * int64(p), sarama.OffsetNewest: 10*int64(p) + 5} {
+				got, err := batch.offset(client, topic, p, at)
+				if err != nil || got != want {
+					t.Errorf("offset(%s/%d, %d) = %d, %v, want %d", topic, p, at, got, err, want)
+				}
+			}
+		}
+	}
+	// по запросу на earliest и latest к единственному брокеру; offset
+	// не обращался к брокеру за партициями из пачки
+	if n := countOffsetRequests(broker); n != 2 {
+		t.Errorf("ListOffsets requests = %d, want 2", n)
+	}
+}
+
+// BenchmarkOffsets сравнивает пачку ListOffsets с запросами GetOffset по
+// каждой партиции. Задержка брокера имитирует сеть: время per-partition
+// растёт с числом партиций, пачки — нет.
+func BenchmarkOffsets(b *testing.B) {
+	const topics, partitions = 20, 12
+	for _, latency := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("batch/latency=%s", latency), func(b *testing.B) {
+			client, _, names := newOffsetsCluster(b, topics, partitions, latency)
+			b.ResetTimer()
+			for range b.N {
+				fetchOffsetBatch(client, names)
+			}
+		})
+		b.Run(fmt.Sprintf("per-partition/latency=%s", latency), func(b *testing.B) {
+			client, _, names := newOffsetsCluster(b, topics, partitions, latency)
+			b.ResetTimer()
+			for range b.N {
+				for _, topic := range names {
+					for p := range int32(partitions) {
+						for _, at := range []int64{sarama.OffsetOldest, sarama.OffsetNewest} {
+							if _, err := client.GetOffset(topic, p, at); err != nil {
+								b.Fatal(err)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,863 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// rowWriter выводит табличный отчёт: Begin — заголовок, WriteRow — очередная
+// строка (значения в порядке заголовка, nil — значение отсутствует),
+// End — завершение вывода.
+type rowWriter interface {
+	Begin() error
+	WriteRow(values []any) error
+	End() error
+}
+
+// renderOptions — настройки отображения значений, общие для всех форматов.
+type renderOptions struct {
+	// Human — числа в табличных форматах выводятся с SI-суффиксами (1.5G)
+	Human bool
+
+	// NullString — как табличные форматы выводят отсутствующее значение
+	NullString string
+
+	// Measurement — имя измерения в формате influx
+	Measurement string
+
+	// FieldNames — переименование полей в JSON-форматах (--json-field-map)
+	FieldNames map[string]string
+
+	// HeaderNames — переименование колонок в строке заголовка CSV (--header-map)
+	HeaderNames map[string]string
+
+	// Brokers и RowsKey — контекст для json-object: список брокеров и имя
+	// поля с массивом строк
+	Brokers []string
+	RowsKey string
+
+	// CollectedAt — значение колонки collected_at (--with-timestamp)
+	CollectedAt time.Time
+
+	// Stable — не выводить меняющиеся от запуска к запуску метаданные:
+	// generated_at в json-object и метку времени influx (--stable)
+	Stable bool
+
+	// Table, CreateTable и ColumnTypes — для формата sql: имя таблицы,
+	// выводить ли CREATE TABLE и типы колонок в терминах JSON Schema
+	// (колонки без типа создаются как TEXT)
+	Table       string
+	CreateTable bool
+	ColumnTypes map[string]string
+}
+
+type outputFormat struct {
+	Name        string
+	Description string
+	// Stream — формат можно писать построчно по мере сбора (--stream):
+	// каждая строка вывода — самостоятельная запись. JSON-документ (json,
+	// json-object) читается только целиком, поэтому к ним это не относится
+	Stream bool
+	New    func(w io.Writer, header []string, opts renderOptions) rowWriter
+}
+
+var outputFormats = []outputFormat{
+	{"csv", "comma-separated values with a header line", true,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &csvWriter{w: w, header: header, opts: opts}
+		}},
+	{"json", "JSON array of row objects", false,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &jsonWriter{w: w, header: renameFields(header, opts.FieldNames)}
+		}},
+	{"jsonl", "one JSON object per line (JSON Lines)", true,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &jsonlWriter{w: w, header: renameFields(header, opts.FieldNames)}
+		}},
+	{"json-object", "single JSON object with generated_at, brokers and the rows array", false,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &jsonObjectWriter{jsonWriter: jsonWriter{w: w, header: renameFields(header, opts.FieldNames), indent: "    "}, opts: opts}
+		}},
+	{"influx", "InfluxDB line protocol: string columns as tags, numbers as fields", true,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &influxWriter{w: w, header: header, measurement: opts.Measurement, stable: opts.Stable}
+		}},
+	{"protobuf", "length-delimited Row messages from proto/row.proto (topics report only)", true,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &protobufWriter{w: w, header: header}
+		}},
+	{"sql", "INSERT statements for --table, optionally preceded by CREATE TABLE", true,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &sqlWriter{w: w, header: header, opts: opts}
+		}},
+	{"tree", "indented tree of dot-separated topic names, messages summed up the hierarchy (topics report only)", false,
+		func(w io.Writer, header []string, opts renderOptions) rowWriter {
+			return &treeWriter{w: w, topic: slices.Index(header, "topic"), messages: slices.Index(header, "messages"), opts: opts, root: &treeNode{}}
+		}},
+}
+
+// withCollectedAt добавляет формату первую колонку collected_at — момент
+// сбора данных из renderOptions.CollectedAt, одинаковый для всех строк.
+func withCollectedAt(f outputFormat) outputFormat {
+	newWriter := f.New
+	f.New = func(w io.Writer, header []string, opts renderOptions) rowWriter {
+		return &collectedAtWriter{
+			rowWriter: newWriter(w, append([]string{"collected_at"}, header...), opts),
+			at:        opts.CollectedAt.UTC().Format(time.RFC3339),
+		}
+	}
+	return f
+}
+
+type collectedAtWriter struct {
+	rowWriter
+	at string
+}
+
+func (cw *collectedAtWriter) WriteRow(values []any) error {
+	return cw.rowWriter.WriteRow(append([]any{cw.at}, values...))
+}
+
+// transposeWarnRows — после скольких строк --transpose предупреждает, что
+// повёрнутая таблица станет слишком широкой.
+const transposeWarnRows = 20
+
+// transposed выводит отчёт формата f повёрнутым (--transpose): колонки
+// становятся строками, строки — колонками, первая колонка каждой строки
+// (обычно topic) — заголовком. Строки копятся до End, поэтому --stream
+// с таким форматом не работает.
+func transposed(f outputFormat) outputFormat {
+	newWriter := f.New
+	f.Stream = false
+	f.New = func(w io.Writer, header []string, opts renderOptions) rowWriter {
+		tw := &transposeWriter{columns: header, header: renameFields(header, opts.HeaderNames), opts: opts}
+		// имена колонок уже переименованы и попадут в ячейки
+		opts.HeaderNames = nil
+		tw.newWriter = func(header []string) rowWriter { return newWriter(w, header, opts) }
+		return tw
+	}
+	return f
+}
+
+type transposeWriter struct {
+	// columns — исходные имена колонок, header — переименованные
+	columns   []string
+	header    []string
+	opts      renderOptions
+	rows      [][]any
+	newWriter func(header []string) rowWriter
+}
+
+func (tw *transposeWriter) Begin() error { return nil }
+
+func (tw *transposeWriter) WriteRow(values []any) error {
+	tw.rows = append(tw.rows, values)
+	return nil
+}
+
+func (tw *transposeWriter) End() error {
+	if len(tw.rows) > transposeWarnRows {
+		log.Printf("WARN: --transpose turns %d rows into columns, it is meant for a few topics", len(tw.rows))
+	}
+	header := make([]string, 0, len(tw.rows)+1)
+	header = append(header, tw.header[0])
+	for _, r := range tw.rows {
+		header = append(header, formatCell(r[0], tw.columns[0], tw.opts))
+	}
+	out := tw.newWriter(header)
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	for i, name := range tw.header[1:] {
+		row := make([]any, 0, len(tw.rows)+1)
+		row = append(row, name)
+		// ячейки форматируются здесь: после поворота колонка значения —
+		// это строка, и --human по заголовку уже не определить
+		for _, r := range tw.rows {
+			row = append(row, formatCell(r[i+1], tw.columns[i+1], tw.opts))
+		}
+		if err := out.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+func findFormat(name string) (outputFormat, error) {
+	names := make([]string, len(outputFormats))
+	for i, f := range outputFormats {
+		if f.Name == name {
+			return f, nil
+		}
+		names[i] = f.Name
+	}
+	return outputFormat{}, fmt.Errorf("unsupported format %q, use one of: %s", name, strings.Join(names, ", "))
+}
+
+func printFormats(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range outputFormats {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Name, f.Description)
+	}
+	tw.Flush()
+}
+
+// parseFieldMap разбирает --json-field-map вида "topic=name,messages=count".
+func parseFieldMap(s string) (map[string]string, error) {
+	names := make(map[string]string)
+	for _, item := range splitList(s) {
+		from, to, ok := strings.Cut(item, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("bad item %q, want column=name", item)
+		}
+		if _, dup := names[from]; dup {
+			return nil, fmt.Errorf("column %q is mapped twice", from)
+		}
+		names[from] = to
+	}
+	return names, nil
+}
+
+// renameFields возвращает заголовок с переименованными полями.
+func renameFields(header []string, names map[string]string) []string {
+	if len(names) == 0 {
+		return header
+	}
+	renamed := make([]string, len(header))
+	for i, name := range header {
+		renamed[i] = cmp.Or(names[name], name)
+	}
+	return renamed
+}
+
+// checkFieldMap проверяет, что все переименованные колонки есть в заголовке
+// и что после переименования имена полей не повторяются.
+func checkFieldMap(header []string, names map[string]string) error {
+	for from := range names {
+		if !slices.Contains(header, from) {
+			return fmt.Errorf("column %q is not in the output, use one of: %s", from, strings.Join(header, ", "))
+		}
+	}
+	seen := make(map[string]bool, len(header))
+	for _, name := range renameFields(header, names) {
+		if seen[name] {
+			return fmt.Errorf("field name %q is used more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		// без экспоненты: 2097152, а не 2.097152e+06
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// humanColumns — колонки-счётчики и величины, которые --human сокращает
+// SI-суффиксами. Идентификаторы (broker_id, partition, leader_epoch) и
+// границы корзин гистограммы выводятся как есть.
+var humanColumns = map[string]bool{
+	"messages":                      true,
+	"offset_at_time":                true,
+	"skew":                          true,
+	"skew_stddev":                   true,
+	"p95_partition_messages":        true,
+	"lag":                           true,
+	"total_lag":                     true,
+	"replica_lag":                   true,
+	"replication_lag":               true,
+	"msgs_per_sec":                  true,
+	"msgs_per_partition":            true,
+	"avg_msgs_per_active_partition": true,
+}
+
+// formatCell выводит значение ячейки колонки column для табличных форматов.
+func formatCell(v any, column string, opts renderOptions) string {
+	if v == nil {
+		return opts.NullString
+	}
+	if opts.Human && humanColumns[column] {
+		switch n := v.(type) {
+		case int32:
+			return humanNumber(float64(n), v)
+		case int64:
+			return humanNumber(float64(n), v)
+		case float64:
+			return humanNumber(n, v)
+		}
+	}
+	return formatValue(v)
+}
+
+// humanNumber сокращает n с SI-суффиксом: 1500000000 → 1.5G.
+// Значения меньше тысячи выводятся как есть.
+func humanNumber(n float64, orig any) string {
+	const units = "kMGTPE"
+	unit := -1
+	for math.Abs(n) >= 1000 && unit < len(units)-1 {
+		n /= 1000
+		unit++
+	}
+	if unit < 0 {
+		return formatValue(orig)
+	}
+	// 999999 после округления не должно превращаться в 1000.0k
+	if math.Abs(n) >= 999.95 && unit < len(units)-1 {
+		n /= 1000
+		unit++
+	}
+	s := strconv.FormatFloat(n, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + string(units[unit])
+}
+
+// csvWriter пишет CSV по RFC 4180: значения с запятыми, кавычками и
+// переводами строк берутся в кавычки. После каждой строки буфер
+// сбрасывается, чтобы --stream выводил строки сразу.
+type csvWriter struct {
+	w      io.Writer
+	header []string
+	opts   renderOptions
+	csv    *csv.Writer
+}
+
+// csvQuotedColumns — колонки, значения которых в CSV всегда берутся
+// в кавычки: список через ";" иначе разбивают на поля программы, читающие
+// CSV с разделителем ";" (Excel в части локалей).
+var csvQuotedColumns = map[string]bool{"leader_brokers": true}
+
+func (cw *csvWriter) Begin() error {
+	cw.csv = csv.NewWriter(cw.w)
+	return cw.write(renameFields(cw.header, cw.opts.HeaderNames))
+}
+
+func (cw *csvWriter) WriteRow(values []any) error {
+	cells := make([]string, len(values))
+	var quoted []bool
+	for i, v := range values {
+		cells[i] = formatCell(v, cw.header[i], cw.opts)
+		if v != nil && csvQuotedColumns[cw.header[i]] {
+			if quoted == nil {
+				quoted = make([]bool, len(values))
+			}
+			quoted[i] = true
+		}
+	}
+	if quoted != nil {
+		return cw.writeQuoted(cells, quoted)
+	}
+	return cw.write(cells)
+}
+
+func (cw *csvWriter) write(record []string) error {
+	if err := cw.csv.Write(record); err != nil {
+		return err
+	}
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// writeQuoted пишет запись, беря в кавычки отмеченные поля независимо от
+// содержимого; остальные поля квотируются по тем же правилам, что
+// у encoding/csv.
+func (cw *csvWriter) writeQuoted(record []string, quoted []bool) error {
+	var line []byte
+	for i, field := range record {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		if !quoted[i] && !csvFieldNeedsQuotes(field) {
+			line = append(line, field...)
+			continue
+		}
+		line = append(line, '"')
+		line = append(line, strings.ReplaceAll(field, `"`, `""`)...)
+		line = append(line, '"')
+	}
+	_, err := cw.w.Write(append(line, '\n'))
+	return err
+}
+
+// csvFieldNeedsQuotes повторяет правило encoding/csv: кавычки нужны полю
+// с запятой, кавычкой, переводом строки, ведущим пробелом или равному \.
+func csvFieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsAny(field, ",\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+func (cw *csvWriter) End() error { return nil }
+
+// jsonObject собирает JSON-объект строки с полями в порядке заголовка.
+func jsonObject(header []string, values []any) ([]byte, error) {
+	buf := []byte{'{'}
+	for i, name := range header {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+type jsonWriter struct {
+	w      io.Writer
+	header []string
+	n      int
+	// indent — отступ строк массива, по умолчанию два пробела
+	indent string
+}
+
+func (jw *jsonWriter) Begin() error {
+	_, err := io.WriteString(jw.w, "[")
+	return err
+}
+
+func (jw *jsonWriter) WriteRow(values []any) error {
+	obj, err := jsonObject(jw.header, values)
+	if err != nil {
+		return err
+	}
+	indent := jw.indent
+	if indent == "" {
+		indent = "  "
+	}
+	sep := ",\n" + indent
+	if jw.n == 0 {
+		sep = "\n" + indent
+	}
+	jw.n++
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(obj)
+	return err
+}
+
+func (jw *jsonWriter) End() error {
+	end := "\n]\n"
+	if jw.n == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+// jsonObjectWriter оборачивает массив строк в объект с метаданными запуска:
+// {"generated_at": ..., "brokers": [...], "<RowsKey>": [...]}.
+type jsonObjectWriter struct {
+	jsonWriter
+	opts renderOptions
+}
+
+func (jw *jsonObjectWriter) Begin() error {
+	var generatedAt []byte
+	if !jw.opts.Stable {
+		ts, err := json.Marshal(time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+		generatedAt = []byte("\n  \"generated_at\": " + string(ts) + ",")
+	}
+	brokers := jw.opts.Brokers
+	if brokers == nil {
+		brokers = []string{}
+	}
+	brokersJSON, err := json.Marshal(brokers)
+	if err != nil {
+		return err
+	}
+	key, err := json.Marshal(cmp.Or(jw.opts.RowsKey, "rows"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(jw.w, "{%s\n  \"brokers\": %s,\n  %s: [", generatedAt, brokersJSON, key)
+	return err
+}
+
+func (jw *jsonObjectWriter) End() error {
+	end := "\n  ]"
+	if jw.n == 0 {
+		end = "]"
+	}
+	errs, err := errorsJSON("  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(jw.w, end+errs+"\n}\n")
+	return err
+}
+
+type jsonlWriter struct {
+	w      io.Writer
+	header []string
+}
+
+func (jw *jsonlWriter) Begin() error { return nil }
+
+func (jw *jsonlWriter) WriteRow(values []any) error {
+	obj, err := jsonObject(jw.header, values)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(jw.w, "%s\n", obj)
+	return err
+}
+
+func (jw *jsonlWriter) End() error { return nil }
+
+// influxWriter выводит строки в line protocol InfluxDB. Колонки-идентификаторы
+// (influxTagColumns) становятся тегами, остальные — полями; пустые значения
+// пропускаются. Все записи получают одну метку времени — момент начала вывода.
+type influxWriter struct {
+	w           io.Writer
+	header      []string
+	measurement string
+	ts          int64
+	// stable — записи без метки времени, её проставит сервер
+	stable bool
+}
+
+var influxTagColumns = map[string]bool{
+	"topic": true, "partition": true, "group": true, "broker_id": true,
+	"principal": true, "operation": true, "permission": true, "host": true,
+	"entity_type": true, "entity_name": true, "quota_type": true,
+	"config_key": true, "status": true, "bucket": true, "member_id": true,
+	"client_id": true, "client_host": true,
+}
+
+var (
+	influxNameEscaper   = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+func (iw *influxWriter) Begin() error {
+	iw.ts = time.Now().UnixNano()
+	return nil
+}
+
+func (iw *influxWriter) WriteRow(values []any) error {
+	var tags, fields []string
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		key := influxTagEscaper.Replace(iw.header[i])
+		if influxTagColumns[iw.header[i]] {
+			// пустое значение тега line protocol не допускает
+			if tag := formatValue(v); tag != "" {
+				tags = append(tags, key+"="+influxTagEscaper.Replace(tag))
+			}
+			continue
+		}
+		switch v := v.(type) {
+		case int32, int64:
+			fields = append(fields, fmt.Sprintf("%s=%di", key, v))
+		case float64:
+			fields = append(fields, key+"="+formatValue(v))
+		case bool:
+			fields = append(fields, key+"="+strconv.FormatBool(v))
+		default:
+			fields = append(fields, key+`="`+influxStringEscaper.Replace(formatValue(v))+`"`)
+		}
+	}
+	// запись без полей line protocol не допускает
+	if len(fields) == 0 {
+		return nil
+	}
+	line := influxNameEscaper.Replace(iw.measurement)
+	if len(tags) > 0 {
+		line += "," + strings.Join(tags, ",")
+	}
+	if iw.stable {
+		_, err := fmt.Fprintf(iw.w, "%s %s\n", line, strings.Join(fields, ","))
+		return err
+	}
+	_, err := fmt.Fprintf(iw.w, "%s %s %d\n", line, strings.Join(fields, ","), iw.ts)
+	return err
+}
+
+func (iw *influxWriter) End() error { return nil }
+
+// sqlWriter выводит строки как INSERT INTO, по оператору на строку.
+// Строки берутся в одинарные кавычки с удвоением кавычек внутри, массивы
+// записываются строкой в JSON, отсутствующие значения — NULL.
+type sqlWriter struct {
+	w      io.Writer
+	header []string
+	opts   renderOptions
+	prefix string
+}
+
+// sqlIdentifierRe — допустимое имя таблицы для --table, возможно со схемой.
+var sqlIdentifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// sqlTypes — типы колонок для CREATE TABLE по типу JSON Schema.
+var sqlTypes = map[string]string{
+	"integer": "BIGINT",
+	"number":  "DOUBLE PRECISION",
+	"boolean": "BOOLEAN",
+}
+
+func (sw *sqlWriter) Begin() error {
+	// имена колонок в кавычках: среди них есть зарезервированные слова (group)
+	names := make([]string, len(sw.header))
+	for i, name := range sw.header {
+		names[i] = `"` + name + `"`
+	}
+	sw.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", sw.opts.Table, strings.Join(names, ","))
+	if !sw.opts.CreateTable {
+		return nil
+	}
+	defs := make([]string, len(sw.header))
+	for i, name := range sw.header {
+		defs[i] = names[i] + " " + cmp.Or(sqlTypes[sw.opts.ColumnTypes[name]], "TEXT")
+	}
+	_, err := fmt.Fprintf(sw.w, "CREATE TABLE IF NOT EXISTS %s (%s);\n", sw.opts.Table, strings.Join(defs, ", "))
+	return err
+}
+
+func (sw *sqlWriter) WriteRow(values []any) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literal, err := sqlLiteral(v)
+		if err != nil {
+			return err
+		}
+		literals[i] = literal
+	}
+	_, err := fmt.Fprintf(sw.w, "%s%s);\n", sw.prefix, strings.Join(literals, ","))
+	return err
+}
+
+func (sw *sqlWriter) End() error { return nil }
+
+func sqlLiteral(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case int32, int64, float64:
+		return formatValue(v), nil
+	case string:
+		return sqlString(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return sqlString(string(data)), nil
+	}
+}
+
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// treeWriter рисует имена топиков деревом по сегментам через точку:
+// orders → orders.created, orders.shipped. У каждого узла — сумма сообщений
+// топиков под ним. Узлы идут в порядке первого появления, то есть
+// в порядке строк отчёта. Строки партиций (повтор топика) пропускаются.
+type treeWriter struct {
+	w        io.Writer
+	topic    int
+	messages int
+	opts     renderOptions
+	root     *treeNode
+	seen     map[string]bool
+}
+
+type treeNode struct {
+	name     string
+	messages int64
+	children []*treeNode
+	index    map[string]*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	if c, ok := n.index[name]; ok {
+		return c
+	}
+	if n.index == nil {
+		n.index = make(map[string]*treeNode)
+	}
+	c := &treeNode{name: name}
+	n.index[name] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+func (tw *treeWriter) Begin() error {
+	tw.seen = make(map[string]bool)
+	return nil
+}
+
+func (tw *treeWriter) WriteRow(values []any) error {
+	name, _ := values[tw.topic].(string)
+	if tw.seen[name] {
+		return nil
+	}
+	tw.seen[name] = true
+	var messages int64
+	if tw.messages >= 0 {
+		messages, _ = values[tw.messages].(int64)
+	}
+	node := tw.root
+	node.messages += messages
+	for _, segment := range strings.Split(name, ".") {
+		node = node.child(segment)
+		node.messages += messages
+	}
+	return nil
+}
+
+func (tw *treeWriter) End() error {
+	for _, c := range tw.root.children {
+		if _, err := fmt.Fprintln(tw.w, tw.label(c)); err != nil {
+			return err
+		}
+		if err := tw.writeChildren(c, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *treeWriter) writeChildren(n *treeNode, indent string) error {
+	for i, c := range n.children {
+		branch, next := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, next = "└── ", "    "
+		}
+		if _, err := fmt.Fprintln(tw.w, indent+branch+tw.label(c)); err != nil {
+			return err
+		}
+		if err := tw.writeChildren(c, indent+next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *treeWriter) label(n *treeNode) string {
+	if tw.messages < 0 {
+		return n.name
+	}
+	return n.name + " (" + formatCell(n.messages, "messages", tw.opts) + ")"
+}
+
+// reportOutput — буферизованный вывод текущего запуска; fatalf сбрасывает
+// его перед выходом.
+var reportOutput *bufferedOutput
+
+// bufferedOutput буферизует вывод отчёта: на кластерах с тысячами топиков
+// запись каждой строки отдельным системным вызовом заметно замедляет вывод.
+// Буфер сбрасывается в Close — при выходе из main, по SIGINT/SIGTERM и
+// в fatalf, — поэтому прерванный запуск не теряет уже сформированные строки.
+type bufferedOutput struct {
+	mu     sync.Mutex
+	buf    *bufio.Writer
+	closer io.Closer // файл --output, nil для stdout
+	// autoFlush сбрасывает буфер после каждой записи (--stream)
+	autoFlush bool
+	closed    bool
+}
+
+func newBufferedOutput(w io.Writer, closer io.Closer, autoFlush bool) *bufferedOutput {
+	return &bufferedOutput{buf: bufio.NewWriterSize(w, 64*1024), closer: closer, autoFlush: autoFlush}
+}
+
+func (o *bufferedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return 0, os.ErrClosed
+	}
+	n, err := o.buf.Write(p)
+	if err == nil && o.autoFlush {
+		err = o.buf.Flush()
+	}
+	return n, err
+}
+
+// Close сбрасывает буфер и закрывает файл вывода. Повторные вызовы ничего
+// не делают, nil-получатель допустим.
+func (o *bufferedOutput) Close() error {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	err := o.buf.Flush()
+	if o.closer != nil {
+		if cerr := o.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// closeOnSignal сбрасывает вывод, выполняет exitCleanups и завершает процесс
+// по SIGINT/SIGTERM с кодом 128+номер сигнала, как это делает shell.
+func (o *bufferedOutput) closeOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		if err := o.Close(); err != nil {
+			log.Printf("WARN: failed to flush output on %s: %v", sig, err)
+		}
+		runExitCleanups()
+		code := 1
+		if s, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(s)
+		}
+		os.Exit(code)
+	}()
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// writeTestRows пишет отчёт из трёх строк CSV без завершения: как запуск,
+// прерванный посреди вывода.
+func writeTestRows(t testing.TB, out *bufferedOutput) {
+	w := outputFormats[0].New(out, []string{"topic", "messages"}, renderOptions{})
+	if err := w.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if err := w.WriteRow([]any{fmt.Sprintf("topic-%d", i), int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const wantTestRows = "topic,messages\ntopic-0,0\ntopic-1,1\ntopic-2,2\n"
+
+func TestBufferedOutputClose(t *testing.T) {
+	var buf bytes.Buffer
+	out := newBufferedOutput(&buf, nil, false)
+	writeTestRows(t, out)
+	if buf.Len() != 0 {
+		t.Fatalf("rows written before Close: %q", buf.String())
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != wantTestRows {
+		t.Errorf("output = %q, want %q", buf.String(), wantTestRows)
+	}
+	if err := out.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if _, err := out.Write([]byte("late\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func TestBufferedOutputAutoFlush(t *testing.T) {
+	var buf bytes.Buffer
+	out := newBufferedOutput(&buf, nil, true)
+	writeTestRows(t, out)
+	if buf.String() != wantTestRows {
+		t.Errorf("output before Close = %q, want %q", buf.String(), wantTestRows)
+	}
+}
+
+// TestBufferedOutputExitHelper — не тест: тело дочернего процесса для
+// TestBufferedOutputFlushOnExit, который завершается через fatalf или сигнал.
+// Файл KTR_TEST_CLEANUP удаляется через atExit, как временный krb5.conf.
+func TestBufferedOutputExitHelper(t *testing.T) {
+	mode := os.Getenv("KTR_TEST_EXIT")
+	if mode == "" {
+		t.Skip("helper process")
+	}
+	atExit(func() { os.Remove(os.Getenv("KTR_TEST_CLEANUP")) })
+	reportOutput = newBufferedOutput(os.Stdout, nil, false)
+	reportOutput.closeOnSignal()
+	writeTestRows(t, reportOutput)
+	switch mode {
+	case "fatalf":
+		fatalf("test failure")
+	case "signal":
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		time.Sleep(10 * time.Second)
+	}
+	t.Fatalf("helper process did not exit")
+}
+
+func TestBufferedOutputFlushOnExit(t *testing.T) {
+	tests := []struct {
+		mode     string
+		wantCode int
+	}{
+		{"fatalf", 1},
+		{"signal", 128 + int(syscall.SIGTERM)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cleanup := filepath.Join(t.TempDir(), "krb5.conf")
+			if err := os.WriteFile(cleanup, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command(os.Args[0], "-test.run=^TestBufferedOutputExitHelper$")
+			cmd.Env = append(os.Environ(), "KTR_TEST_EXIT="+tt.mode, "KTR_TEST_CLEANUP="+cleanup)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			err := cmd.Run()
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok || exitErr.ExitCode() != tt.wantCode {
+				t.Fatalf("helper exit = %v, want code %d; stderr:\n%s", err, tt.wantCode, stderr.String())
+			}
+			if !strings.HasPrefix(stdout.String(), wantTestRows) {
+				t.Errorf("output = %q, want rows %q", stdout.String(), wantTestRows)
+			}
+			if _, err := os.Stat(cleanup); !os.IsNotExist(err) {
+				t.Errorf("exit cleanup did not run: stat %s: %v", cleanup, err)
+			}
+		})
+	}
+}
+
+// csvRoundTrip пишет строки через csvWriter и читает их обратно encoding/csv.
+func csvRoundTrip(t testing.TB, topics []string) [][]string {
+	var buf bytes.Buffer
+	w := &csvWriter{w: &buf, header: []string{"topic", "messages"}}
+	if err := w.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	for i, topic := range topics {
+		if err := w.WriteRow([]any{topic, int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading %q: %v", buf.String(), err)
+	}
+	return records
+}
+
+func TestCSVWriterQuoting(t *testing.T) {
+	topics := []string{
+		"orders",
+		"orders,eu",
+		`say "hi"`,
+		"multi\nline",
+		"crlf\r\nline",
+		"=HYPERLINK(\"http://x\")",
+		" padded ",
+		"",
+		`","`,
+	}
+	records := csvRoundTrip(t, topics)
+	if len(records) != len(topics)+1 {
+		t.Fatalf("got %d records, want %d: %q", len(records), len(topics)+1, records)
+	}
+	for i, topic := range topics {
+		want := []string{topic, strconv.Itoa(i)}
+		// encoding/csv читает \r\n внутри поля как \n
+		want[0] = strings.ReplaceAll(want[0], "\r\n", "\n")
+		if !reflect.DeepEqual(records[i+1], want) {
+			t.Errorf("record %d = %q, want %q", i+1, records[i+1], want)
+		}
+	}
+}
+
+func FuzzCSVWriter(f *testing.F) {
+	for _, s := range []string{"orders", "a,b", `"q"`, "x\ny", "=1+1"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, topic string) {
+		if !utf8.ValidString(topic) || strings.ContainsRune(topic, '\r') {
+			t.Skip()
+		}
+		records := csvRoundTrip(t, []string{topic})
+		if len(records) != 2 || len(records[1]) != 2 || records[1][0] != topic {
+			t.Errorf("round trip of %q = %q", topic, records)
+		}
+	})
+}
+
+func TestPrintFormatsAligned(t *testing.T) {
+	var buf bytes.Buffer
+	printFormats(&buf)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(outputFormats) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(outputFormats))
+	}
+	column := -1
+	for i, line := range lines {
+		f := outputFormats[i]
+		if !strings.HasPrefix(line, f.Name+" ") {
+			t.Errorf("line %q does not start with %s", line, f.Name)
+		}
+		at := strings.Index(line, f.Description)
+		if column == -1 {
+			column = at
+		}
+		if at != column || at <= len(f.Name) {
+			t.Errorf("description of %s at column %d, want %d: %q", f.Name, at, column, line)
+		}
+	}
+}
+
+// JSON-документ не читается, пока не записан целиком, поэтому --stream
+// ему ничего не даёт.
+func TestJSONFormatsNotStreamed(t *testing.T) {
+	for _, name := range []string{"json", "json-object"} {
+		f, err := findFormat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Stream {
+			t.Errorf("%s: Stream = true, want false", name)
+		}
+	}
+}
+
+func TestCSVWriterQuotesLeaderBrokers(t *testing.T) {
+	var buf bytes.Buffer
+	w := &csvWriter{w: &buf, header: []string{"topic", "leader_brokers", "messages"}}
+	rows := [][]any{
+		{"orders", "b1:9092;b2:9092", int64(1)},
+		{"with,comma \"q\"", "b1:9092", int64(2)},
+		{" multi\nline", nil, int64(3)},
+	}
+	if err := w.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if err := w.WriteRow(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw := buf.String()
+	for _, want := range []string{"orders,\"b1:9092;b2:9092\",1\n", "\"with,comma \"\"q\"\"\",\"b1:9092\",2\n", "\" multi\nline\",,3\n"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("output %q does not contain %q", raw, want)
+		}
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"topic", "leader_brokers", "messages"},
+		{"orders", "b1:9092;b2:9092", "1"},
+		{"with,comma \"q\"", "b1:9092", "2"},
+		{" multi\nline", "", "3"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+// FuzzCSVFieldNeedsQuotes сверяет csvFieldNeedsQuotes с encoding/csv.
+func FuzzCSVFieldNeedsQuotes(f *testing.F) {
+	for _, s := range []string{"", "a", "a,b", " a", `\.`, "\tx", "q\"", " x"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, field string) {
+		if !utf8.ValidString(field) {
+			t.Skip()
+		}
+		var buf bytes.Buffer
+		cw := csv.NewWriter(&buf)
+		cw.Write([]string{field, "x"})
+		cw.Flush()
+		if got, want := csvFieldNeedsQuotes(field), strings.HasPrefix(buf.String(), `"`); got != want {
+			t.Errorf("csvFieldNeedsQuotes(%q) = %t, encoding/csv quotes: %t", field, got, want)
+		}
+	})
+}
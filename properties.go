@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// readProperties читает файл свойств в формате Java: key=value или
+// key: value, комментарии # и !, продолжение строки обратной косой чертой.
+func readProperties(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	props := make(map[string]string)
+	var logical string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if logical == "" && (line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!")) {
+			continue
+		}
+		if strings.HasSuffix(line, `\`) && !strings.HasSuffix(line, `\\`) {
+			logical += strings.TrimSuffix(line, `\`)
+			continue
+		}
+		logical += line
+		key, value := splitProperty(logical)
+		props[key] = value
+		logical = ""
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if logical != "" {
+		key, value := splitProperty(logical)
+		props[key] = value
+	}
+	return props, nil
+}
+
+// splitProperty делит строку по первому '=' или ':'.
+func splitProperty(line string) (key, value string) {
+	i := strings.IndexAny(line, "=:")
+	if i < 0 {
+		return strings.TrimSpace(line), ""
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+}
+
+var jaasOptionRe = regexp.MustCompile(`(\w+)\s*=\s*"((?:[^"\\]|\\.)*)"`)
+
+// jaasCredentials достаёт username и password из sasl.jaas.config для
+// PlainLoginModule и ScramLoginModule.
+func jaasCredentials(jaas string) (saslCredentials, error) {
+	if !strings.Contains(jaas, "PlainLoginModule") && !strings.Contains(jaas, "ScramLoginModule") {
+		return saslCredentials{}, fmt.Errorf("unsupported login module, use PlainLoginModule or ScramLoginModule")
+	}
+	var creds saslCredentials
+	for _, m := range jaasOptionRe.FindAllStringSubmatch(jaas, -1) {
+		value := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(m[2])
+		switch m[1] {
+		case "username":
+			creds.Username = value
+		case "password":
+			creds.Password = value
+		}
+	}
+	if creds.Username == "" || creds.Password == "" {
+		return saslCredentials{}, fmt.Errorf("username and password are required")
+	}
+	return creds, nil
+}
+
+// commandConfig — настройки подключения из --command-config.
+type commandConfig struct {
+	SecurityProtocol string
+	SASLMechanism    string
+	Credentials      saslCredentials
+}
+
+// parseCommandConfig разбирает client.properties Kafka CLI. Свойства, которые
+// не поддерживаются, перечисляются в предупреждении.
+func parseCommandConfig(path string) (commandConfig, error) {
+	props, err := readProperties(path)
+	if err != nil {
+		return commandConfig{}, err
+	}
+	var cc commandConfig
+	var ignored []string
+	for key, value := range props {
+		switch key {
+		case "security.protocol":
+			cc.SecurityProtocol = value
+		case "sasl.mechanism":
+			cc.SASLMechanism = value
+		case "sasl.jaas.config":
+			cc.Credentials, err = jaasCredentials(value)
+			if err != nil {
+				return commandConfig{}, fmt.Errorf("sasl.jaas.config: %w", err)
+			}
+		default:
+			ignored = append(ignored, key)
+		}
+	}
+	if len(ignored) > 0 {
+		slices.Sort(ignored)
+		log.Printf("WARN: %s: ignoring unsupported properties: %s", path, strings.Join(ignored, ", "))
+	}
+	return cc, nil
+}
+
+// apply подставляет настройки из файла в те значения, которые не заданы
+// флагами командной строки.
+func (cc commandConfig) apply(protocol, mechanism *string, creds *saslCredentials) {
+	if !flagExplicit("security-protocol") && cc.SecurityProtocol != "" {
+		*protocol = cc.SecurityProtocol
+	}
+	if !flagExplicit("sasl-mechanism") && cc.SASLMechanism != "" {
+		*mechanism = cc.SASLMechanism
+	}
+	if !flagExplicit("sasl-username") && cc.Credentials.Username != "" {
+		creds.Username = cc.Credentials.Username
+	}
+	if !flagExplicit("sasl-password") && cc.Credentials.Password != "" {
+		creds.Password = cc.Credentials.Password
+	}
+}
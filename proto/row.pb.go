@@ -0,0 +1,587 @@
+// Схема строк отчёта по топикам для --format protobuf.
+//
+// Каждая строка выводится отдельным сообщением Row с префиксом длины
+// (varint), как пишет writeDelimitedTo в Java и читает parseDelimitedFrom.
+// Поля заполнены только для колонок, включённых флагами; отсутствующее
+// значение (пустая ячейка CSV) не передаётся вовсе.
+//
+// Go-код в row.pb.go генерируется protoc-gen-go: go generate в корне
+// репозитория.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: proto/row.proto
+
+package rowpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Row struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Topic string `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	// только в строках партиций
+	Partition          *int32 `protobuf:"varint,2,opt,name=partition,proto3,oneof" json:"partition,omitempty"`
+	Partitions         *int32 `protobuf:"varint,3,opt,name=partitions,proto3,oneof" json:"partitions,omitempty"`
+	Consumers          *int64 `protobuf:"varint,4,opt,name=consumers,proto3,oneof" json:"consumers,omitempty"`
+	Messages           *int64 `protobuf:"varint,5,opt,name=messages,proto3,oneof" json:"messages,omitempty"`
+	PartitionsExpected *int32 `protobuf:"varint,6,opt,name=partitions_expected,json=partitionsExpected,proto3,oneof" json:"partitions_expected,omitempty"`
+	Matches            *bool  `protobuf:"varint,7,opt,name=matches,proto3,oneof" json:"matches,omitempty"`
+	SingleReplica      *bool  `protobuf:"varint,8,opt,name=single_replica,json=singleReplica,proto3,oneof" json:"single_replica,omitempty"`
+	// длительность в формате Go, например "1h2m3s"
+	LastCommitAge            *string  `protobuf:"bytes,9,opt,name=last_commit_age,json=lastCommitAge,proto3,oneof" json:"last_commit_age,omitempty"`
+	OldestMessageAge         *string  `protobuf:"bytes,10,opt,name=oldest_message_age,json=oldestMessageAge,proto3,oneof" json:"oldest_message_age,omitempty"`
+	OffsetAtTime             *int64   `protobuf:"varint,11,opt,name=offset_at_time,json=offsetAtTime,proto3,oneof" json:"offset_at_time,omitempty"`
+	Category                 *string  `protobuf:"bytes,12,opt,name=category,proto3,oneof" json:"category,omitempty"`
+	Lag                      *int64   `protobuf:"varint,13,opt,name=lag,proto3,oneof" json:"lag,omitempty"`
+	GroupsNoCommit           []string `protobuf:"bytes,14,rep,name=groups_no_commit,json=groupsNoCommit,proto3" json:"groups_no_commit,omitempty"`
+	MsgsPerSec               *float64 `protobuf:"fixed64,15,opt,name=msgs_per_sec,json=msgsPerSec,proto3,oneof" json:"msgs_per_sec,omitempty"`
+	Replicas                 []int32  `protobuf:"varint,16,rep,packed,name=replicas,proto3" json:"replicas,omitempty"`
+	Isr                      []int32  `protobuf:"varint,17,rep,packed,name=isr,proto3" json:"isr,omitempty"`
+	NonPreferredLeaders      *int32   `protobuf:"varint,18,opt,name=non_preferred_leaders,json=nonPreferredLeaders,proto3,oneof" json:"non_preferred_leaders,omitempty"`
+	PreferredLeaderImbalance *bool    `protobuf:"varint,19,opt,name=preferred_leader_imbalance,json=preferredLeaderImbalance,proto3,oneof" json:"preferred_leader_imbalance,omitempty"`
+	ReplicaLag               *int64   `protobuf:"varint,20,opt,name=replica_lag,json=replicaLag,proto3,oneof" json:"replica_lag,omitempty"`
+	Incomplete               *bool    `protobuf:"varint,21,opt,name=incomplete,proto3,oneof" json:"incomplete,omitempty"`
+	LeaderEpoch              *int32   `protobuf:"varint,22,opt,name=leader_epoch,json=leaderEpoch,proto3,oneof" json:"leader_epoch,omitempty"`
+	TotalLag                 *int64   `protobuf:"varint,23,opt,name=total_lag,json=totalLag,proto3,oneof" json:"total_lag,omitempty"`
+	// время сбора в RFC3339 (--with-timestamp)
+	CollectedAt               *string  `protobuf:"bytes,24,opt,name=collected_at,json=collectedAt,proto3,oneof" json:"collected_at,omitempty"`
+	ReplicationFactor         *int32   `protobuf:"varint,25,opt,name=replication_factor,json=replicationFactor,proto3,oneof" json:"replication_factor,omitempty"`
+	MinIsr                    *int32   `protobuf:"varint,26,opt,name=min_isr,json=minIsr,proto3,oneof" json:"min_isr,omitempty"`
+	Durable                   *bool    `protobuf:"varint,27,opt,name=durable,proto3,oneof" json:"durable,omitempty"`
+	ReplicationLag            *int64   `protobuf:"varint,28,opt,name=replication_lag,json=replicationLag,proto3,oneof" json:"replication_lag,omitempty"`
+	Skew                      *int64   `protobuf:"varint,29,opt,name=skew,proto3,oneof" json:"skew,omitempty"`
+	SkewStddev                *float64 `protobuf:"fixed64,30,opt,name=skew_stddev,json=skewStddev,proto3,oneof" json:"skew_stddev,omitempty"`
+	SkewCv                    *float64 `protobuf:"fixed64,31,opt,name=skew_cv,json=skewCv,proto3,oneof" json:"skew_cv,omitempty"`
+	ActivePartitions          *int32   `protobuf:"varint,32,opt,name=active_partitions,json=activePartitions,proto3,oneof" json:"active_partitions,omitempty"`
+	AvgMsgsPerActivePartition *float64 `protobuf:"fixed64,33,opt,name=avg_msgs_per_active_partition,json=avgMsgsPerActivePartition,proto3,oneof" json:"avg_msgs_per_active_partition,omitempty"`
+	MsgsPerPartition          *float64 `protobuf:"fixed64,34,opt,name=msgs_per_partition,json=msgsPerPartition,proto3,oneof" json:"msgs_per_partition,omitempty"`
+	P95PartitionMessages      *int64   `protobuf:"varint,35,opt,name=p95_partition_messages,json=p95PartitionMessages,proto3,oneof" json:"p95_partition_messages,omitempty"`
+	NeverWritten              *bool    `protobuf:"varint,36,opt,name=never_written,json=neverWritten,proto3,oneof" json:"never_written,omitempty"`
+	GroupCount                *int32   `protobuf:"varint,37,opt,name=group_count,json=groupCount,proto3,oneof" json:"group_count,omitempty"`
+	// адреса брокеров-лидеров через ";"
+	LeaderBrokers *string `protobuf:"bytes,38,opt,name=leader_brokers,json=leaderBrokers,proto3,oneof" json:"leader_brokers,omitempty"`
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_row_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_row_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_proto_row_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Row) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *Row) GetPartition() int32 {
+	if x != nil && x.Partition != nil {
+		return *x.Partition
+	}
+	return 0
+}
+
+func (x *Row) GetPartitions() int32 {
+	if x != nil && x.Partitions != nil {
+		return *x.Partitions
+	}
+	return 0
+}
+
+func (x *Row) GetConsumers() int64 {
+	if x != nil && x.Consumers != nil {
+		return *x.Consumers
+	}
+	return 0
+}
+
+func (x *Row) GetMessages() int64 {
+	if x != nil && x.Messages != nil {
+		return *x.Messages
+	}
+	return 0
+}
+
+func (x *Row) GetPartitionsExpected() int32 {
+	if x != nil && x.PartitionsExpected != nil {
+		return *x.PartitionsExpected
+	}
+	return 0
+}
+
+func (x *Row) GetMatches() bool {
+	if x != nil && x.Matches != nil {
+		return *x.Matches
+	}
+	return false
+}
+
+func (x *Row) GetSingleReplica() bool {
+	if x != nil && x.SingleReplica != nil {
+		return *x.SingleReplica
+	}
+	return false
+}
+
+func (x *Row) GetLastCommitAge() string {
+	if x != nil && x.LastCommitAge != nil {
+		return *x.LastCommitAge
+	}
+	return ""
+}
+
+func (x *Row) GetOldestMessageAge() string {
+	if x != nil && x.OldestMessageAge != nil {
+		return *x.OldestMessageAge
+	}
+	return ""
+}
+
+func (x *Row) GetOffsetAtTime() int64 {
+	if x != nil && x.OffsetAtTime != nil {
+		return *x.OffsetAtTime
+	}
+	return 0
+}
+
+func (x *Row) GetCategory() string {
+	if x != nil && x.Category != nil {
+		return *x.Category
+	}
+	return ""
+}
+
+func (x *Row) GetLag() int64 {
+	if x != nil && x.Lag != nil {
+		return *x.Lag
+	}
+	return 0
+}
+
+func (x *Row) GetGroupsNoCommit() []string {
+	if x != nil {
+		return x.GroupsNoCommit
+	}
+	return nil
+}
+
+func (x *Row) GetMsgsPerSec() float64 {
+	if x != nil && x.MsgsPerSec != nil {
+		return *x.MsgsPerSec
+	}
+	return 0
+}
+
+func (x *Row) GetReplicas() []int32 {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+func (x *Row) GetIsr() []int32 {
+	if x != nil {
+		return x.Isr
+	}
+	return nil
+}
+
+func (x *Row) GetNonPreferredLeaders() int32 {
+	if x != nil && x.NonPreferredLeaders != nil {
+		return *x.NonPreferredLeaders
+	}
+	return 0
+}
+
+func (x *Row) GetPreferredLeaderImbalance() bool {
+	if x != nil && x.PreferredLeaderImbalance != nil {
+		return *x.PreferredLeaderImbalance
+	}
+	return false
+}
+
+func (x *Row) GetReplicaLag() int64 {
+	if x != nil && x.ReplicaLag != nil {
+		return *x.ReplicaLag
+	}
+	return 0
+}
+
+func (x *Row) GetIncomplete() bool {
+	if x != nil && x.Incomplete != nil {
+		return *x.Incomplete
+	}
+	return false
+}
+
+func (x *Row) GetLeaderEpoch() int32 {
+	if x != nil && x.LeaderEpoch != nil {
+		return *x.LeaderEpoch
+	}
+	return 0
+}
+
+func (x *Row) GetTotalLag() int64 {
+	if x != nil && x.TotalLag != nil {
+		return *x.TotalLag
+	}
+	return 0
+}
+
+func (x *Row) GetCollectedAt() string {
+	if x != nil && x.CollectedAt != nil {
+		return *x.CollectedAt
+	}
+	return ""
+}
+
+func (x *Row) GetReplicationFactor() int32 {
+	if x != nil && x.ReplicationFactor != nil {
+		return *x.ReplicationFactor
+	}
+	return 0
+}
+
+func (x *Row) GetMinIsr() int32 {
+	if x != nil && x.MinIsr != nil {
+		return *x.MinIsr
+	}
+	return 0
+}
+
+func (x *Row) GetDurable() bool {
+	if x != nil && x.Durable != nil {
+		return *x.Durable
+	}
+	return false
+}
+
+func (x *Row) GetReplicationLag() int64 {
+	if x != nil && x.ReplicationLag != nil {
+		return *x.ReplicationLag
+	}
+	return 0
+}
+
+func (x *Row) GetSkew() int64 {
+	if x != nil && x.Skew != nil {
+		return *x.Skew
+	}
+	return 0
+}
+
+func (x *Row) GetSkewStddev() float64 {
+	if x != nil && x.SkewStddev != nil {
+		return *x.SkewStddev
+	}
+	return 0
+}
+
+func (x *Row) GetSkewCv() float64 {
+	if x != nil && x.SkewCv != nil {
+		return *x.SkewCv
+	}
+	return 0
+}
+
+func (x *Row) GetActivePartitions() int32 {
+	if x != nil && x.ActivePartitions != nil {
+		return *x.ActivePartitions
+	}
+	return 0
+}
+
+func (x *Row) GetAvgMsgsPerActivePartition() float64 {
+	if x != nil && x.AvgMsgsPerActivePartition != nil {
+		return *x.AvgMsgsPerActivePartition
+	}
+	return 0
+}
+
+func (x *Row) GetMsgsPerPartition() float64 {
+	if x != nil && x.MsgsPerPartition != nil {
+		return *x.MsgsPerPartition
+	}
+	return 0
+}
+
+func (x *Row) GetP95PartitionMessages() int64 {
+	if x != nil && x.P95PartitionMessages != nil {
+		return *x.P95PartitionMessages
+	}
+	return 0
+}
+
+func (x *Row) GetNeverWritten() bool {
+	if x != nil && x.NeverWritten != nil {
+		return *x.NeverWritten
+	}
+	return false
+}
+
+func (x *Row) GetGroupCount() int32 {
+	if x != nil && x.GroupCount != nil {
+		return *x.GroupCount
+	}
+	return 0
+}
+
+func (x *Row) GetLeaderBrokers() string {
+	if x != nil && x.LeaderBrokers != nil {
+		return *x.LeaderBrokers
+	}
+	return ""
+}
+
+var File_proto_row_proto protoreflect.FileDescriptor
+
+var file_proto_row_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x72, 0x6f, 0x77, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x13, 0x6b, 0x61, 0x66, 0x6b, 0x61, 0x5f, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x73, 0x5f,
+	0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0xe4, 0x10, 0x0a, 0x03, 0x52, 0x6f, 0x77, 0x12, 0x14,
+	0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74,
+	0x6f, 0x70, 0x69, 0x63, 0x12, 0x21, 0x0a, 0x09, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x09, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x0a, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x0a, 0x70,
+	0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x88, 0x01, 0x01, 0x12, 0x21, 0x0a, 0x09,
+	0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x48,
+	0x02, 0x52, 0x09, 0x63, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x88, 0x01, 0x01, 0x12,
+	0x1f, 0x0a, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x03, 0x48, 0x03, 0x52, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x88, 0x01, 0x01,
+	0x12, 0x34, 0x0a, 0x13, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x5f, 0x65,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x48, 0x04, 0x52,
+	0x12, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x45, 0x78, 0x70, 0x65, 0x63,
+	0x74, 0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x07, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x65,
+	0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x48, 0x05, 0x52, 0x07, 0x6d, 0x61, 0x74, 0x63, 0x68,
+	0x65, 0x73, 0x88, 0x01, 0x01, 0x12, 0x2a, 0x0a, 0x0e, 0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x5f,
+	0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x48, 0x06, 0x52,
+	0x0d, 0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x88, 0x01,
+	0x01, 0x12, 0x2b, 0x0a, 0x0f, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x5f, 0x61, 0x67, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x48, 0x07, 0x52, 0x0d, 0x6c, 0x61,
+	0x73, 0x74, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x41, 0x67, 0x65, 0x88, 0x01, 0x01, 0x12, 0x31,
+	0x0a, 0x12, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
+	0x5f, 0x61, 0x67, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x48, 0x08, 0x52, 0x10, 0x6f, 0x6c,
+	0x64, 0x65, 0x73, 0x74, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x41, 0x67, 0x65, 0x88, 0x01,
+	0x01, 0x12, 0x29, 0x0a, 0x0e, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x5f, 0x61, 0x74, 0x5f, 0x74,
+	0x69, 0x6d, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x48, 0x09, 0x52, 0x0c, 0x6f, 0x66, 0x66,
+	0x73, 0x65, 0x74, 0x41, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1f, 0x0a, 0x08,
+	0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x48, 0x0a,
+	0x52, 0x08, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x79, 0x88, 0x01, 0x01, 0x12, 0x15, 0x0a,
+	0x03, 0x6c, 0x61, 0x67, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x48, 0x0b, 0x52, 0x03, 0x6c, 0x61,
+	0x67, 0x88, 0x01, 0x01, 0x12, 0x28, 0x0a, 0x10, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x5f, 0x6e,
+	0x6f, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x18, 0x0e, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x4e, 0x6f, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x25,
+	0x0a, 0x0c, 0x6d, 0x73, 0x67, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x01, 0x48, 0x0c, 0x52, 0x0a, 0x6d, 0x73, 0x67, 0x73, 0x50, 0x65, 0x72, 0x53,
+	0x65, 0x63, 0x88, 0x01, 0x01, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61,
+	0x73, 0x18, 0x10, 0x20, 0x03, 0x28, 0x05, 0x52, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61,
+	0x73, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x73, 0x72, 0x18, 0x11, 0x20, 0x03, 0x28, 0x05, 0x52, 0x03,
+	0x69, 0x73, 0x72, 0x12, 0x37, 0x0a, 0x15, 0x6e, 0x6f, 0x6e, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x65,
+	0x72, 0x72, 0x65, 0x64, 0x5f, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x12, 0x20, 0x01,
+	0x28, 0x05, 0x48, 0x0d, 0x52, 0x13, 0x6e, 0x6f, 0x6e, 0x50, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72,
+	0x65, 0x64, 0x4c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x88, 0x01, 0x01, 0x12, 0x41, 0x0a, 0x1a,
+	0x70, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x5f, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x5f, 0x69, 0x6d, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x13, 0x20, 0x01, 0x28, 0x08,
+	0x48, 0x0e, 0x52, 0x18, 0x70, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x4c, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x49, 0x6d, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x88, 0x01, 0x01, 0x12,
+	0x24, 0x0a, 0x0b, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x5f, 0x6c, 0x61, 0x67, 0x18, 0x14,
+	0x20, 0x01, 0x28, 0x03, 0x48, 0x0f, 0x52, 0x0a, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x4c,
+	0x61, 0x67, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x0a, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x6c,
+	0x65, 0x74, 0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x08, 0x48, 0x10, 0x52, 0x0a, 0x69, 0x6e, 0x63,
+	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x6c, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x5f, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x18, 0x16, 0x20, 0x01, 0x28, 0x05,
+	0x48, 0x11, 0x52, 0x0b, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x45, 0x70, 0x6f, 0x63, 0x68, 0x88,
+	0x01, 0x01, 0x12, 0x20, 0x0a, 0x09, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x6c, 0x61, 0x67, 0x18,
+	0x17, 0x20, 0x01, 0x28, 0x03, 0x48, 0x12, 0x52, 0x08, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x4c, 0x61,
+	0x67, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x5f, 0x61, 0x74, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09, 0x48, 0x13, 0x52, 0x0b, 0x63, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x41, 0x74, 0x88, 0x01, 0x01, 0x12, 0x32, 0x0a, 0x12,
+	0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x66, 0x61, 0x63, 0x74,
+	0x6f, 0x72, 0x18, 0x19, 0x20, 0x01, 0x28, 0x05, 0x48, 0x14, 0x52, 0x11, 0x72, 0x65, 0x70, 0x6c,
+	0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x88, 0x01, 0x01,
+	0x12, 0x1c, 0x0a, 0x07, 0x6d, 0x69, 0x6e, 0x5f, 0x69, 0x73, 0x72, 0x18, 0x1a, 0x20, 0x01, 0x28,
+	0x05, 0x48, 0x15, 0x52, 0x06, 0x6d, 0x69, 0x6e, 0x49, 0x73, 0x72, 0x88, 0x01, 0x01, 0x12, 0x1d,
+	0x0a, 0x07, 0x64, 0x75, 0x72, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x08, 0x48,
+	0x16, 0x52, 0x07, 0x64, 0x75, 0x72, 0x61, 0x62, 0x6c, 0x65, 0x88, 0x01, 0x01, 0x12, 0x2c, 0x0a,
+	0x0f, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x61, 0x67,
+	0x18, 0x1c, 0x20, 0x01, 0x28, 0x03, 0x48, 0x17, 0x52, 0x0e, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x61, 0x67, 0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x73,
+	0x6b, 0x65, 0x77, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x03, 0x48, 0x18, 0x52, 0x04, 0x73, 0x6b, 0x65,
+	0x77, 0x88, 0x01, 0x01, 0x12, 0x24, 0x0a, 0x0b, 0x73, 0x6b, 0x65, 0x77, 0x5f, 0x73, 0x74, 0x64,
+	0x64, 0x65, 0x76, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x01, 0x48, 0x19, 0x52, 0x0a, 0x73, 0x6b, 0x65,
+	0x77, 0x53, 0x74, 0x64, 0x64, 0x65, 0x76, 0x88, 0x01, 0x01, 0x12, 0x1c, 0x0a, 0x07, 0x73, 0x6b,
+	0x65, 0x77, 0x5f, 0x63, 0x76, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x01, 0x48, 0x1a, 0x52, 0x06, 0x73,
+	0x6b, 0x65, 0x77, 0x43, 0x76, 0x88, 0x01, 0x01, 0x12, 0x30, 0x0a, 0x11, 0x61, 0x63, 0x74, 0x69,
+	0x76, 0x65, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x20, 0x20,
+	0x01, 0x28, 0x05, 0x48, 0x1b, 0x52, 0x10, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x61, 0x72,
+	0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x88, 0x01, 0x01, 0x12, 0x45, 0x0a, 0x1d, 0x61, 0x76,
+	0x67, 0x5f, 0x6d, 0x73, 0x67, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76,
+	0x65, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x21, 0x20, 0x01, 0x28,
+	0x01, 0x48, 0x1c, 0x52, 0x19, 0x61, 0x76, 0x67, 0x4d, 0x73, 0x67, 0x73, 0x50, 0x65, 0x72, 0x41,
+	0x63, 0x74, 0x69, 0x76, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x88, 0x01,
+	0x01, 0x12, 0x31, 0x0a, 0x12, 0x6d, 0x73, 0x67, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x61,
+	0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x22, 0x20, 0x01, 0x28, 0x01, 0x48, 0x1d, 0x52,
+	0x10, 0x6d, 0x73, 0x67, 0x73, 0x50, 0x65, 0x72, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x88, 0x01, 0x01, 0x12, 0x39, 0x0a, 0x16, 0x70, 0x39, 0x35, 0x5f, 0x70, 0x61, 0x72, 0x74,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x18, 0x23,
+	0x20, 0x01, 0x28, 0x03, 0x48, 0x1e, 0x52, 0x14, 0x70, 0x39, 0x35, 0x50, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12,
+	0x28, 0x0a, 0x0d, 0x6e, 0x65, 0x76, 0x65, 0x72, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e,
+	0x18, 0x24, 0x20, 0x01, 0x28, 0x08, 0x48, 0x1f, 0x52, 0x0c, 0x6e, 0x65, 0x76, 0x65, 0x72, 0x57,
+	0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x24, 0x0a, 0x0b, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x25, 0x20, 0x01, 0x28, 0x05, 0x48, 0x20,
+	0x52, 0x0a, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x88, 0x01, 0x01, 0x12,
+	0x2a, 0x0a, 0x0e, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72,
+	0x73, 0x18, 0x26, 0x20, 0x01, 0x28, 0x09, 0x48, 0x21, 0x52, 0x0d, 0x6c, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x88, 0x01, 0x01, 0x42, 0x0c, 0x0a, 0x0a, 0x5f,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x70, 0x61,
+	0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x63, 0x6f, 0x6e,
+	0x73, 0x75, 0x6d, 0x65, 0x72, 0x73, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x73, 0x42, 0x16, 0x0a, 0x14, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x5f, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x42, 0x0a, 0x0a, 0x08, 0x5f,
+	0x6d, 0x61, 0x74, 0x63, 0x68, 0x65, 0x73, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x73, 0x69, 0x6e, 0x67,
+	0x6c, 0x65, 0x5f, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x42, 0x12, 0x0a, 0x10, 0x5f, 0x6c,
+	0x61, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x61, 0x67, 0x65, 0x42, 0x15,
+	0x0a, 0x13, 0x5f, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67,
+	0x65, 0x5f, 0x61, 0x67, 0x65, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74,
+	0x5f, 0x61, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x63, 0x61, 0x74,
+	0x65, 0x67, 0x6f, 0x72, 0x79, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x6c, 0x61, 0x67, 0x42, 0x0f, 0x0a,
+	0x0d, 0x5f, 0x6d, 0x73, 0x67, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x42, 0x18,
+	0x0a, 0x16, 0x5f, 0x6e, 0x6f, 0x6e, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64,
+	0x5f, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x42, 0x1d, 0x0a, 0x1b, 0x5f, 0x70, 0x72, 0x65,
+	0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x5f, 0x6c, 0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x69, 0x6d,
+	0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x42, 0x0e, 0x0a, 0x0c, 0x5f, 0x72, 0x65, 0x70, 0x6c,
+	0x69, 0x63, 0x61, 0x5f, 0x6c, 0x61, 0x67, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x69, 0x6e, 0x63, 0x6f,
+	0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x6c, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x5f, 0x65, 0x70, 0x6f, 0x63, 0x68, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x5f, 0x6c, 0x61, 0x67, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x72, 0x65, 0x70, 0x6c, 0x69,
+	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x66, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x42, 0x0a, 0x0a,
+	0x08, 0x5f, 0x6d, 0x69, 0x6e, 0x5f, 0x69, 0x73, 0x72, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x64, 0x75,
+	0x72, 0x61, 0x62, 0x6c, 0x65, 0x42, 0x12, 0x0a, 0x10, 0x5f, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x61, 0x67, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x73, 0x6b,
+	0x65, 0x77, 0x42, 0x0e, 0x0a, 0x0c, 0x5f, 0x73, 0x6b, 0x65, 0x77, 0x5f, 0x73, 0x74, 0x64, 0x64,
+	0x65, 0x76, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x73, 0x6b, 0x65, 0x77, 0x5f, 0x63, 0x76, 0x42, 0x14,
+	0x0a, 0x12, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x42, 0x20, 0x0a, 0x1e, 0x5f, 0x61, 0x76, 0x67, 0x5f, 0x6d, 0x73, 0x67,
+	0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x61, 0x63, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x70, 0x61, 0x72,
+	0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x15, 0x0a, 0x13, 0x5f, 0x6d, 0x73, 0x67, 0x73, 0x5f,
+	0x70, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x19, 0x0a,
+	0x17, 0x5f, 0x70, 0x39, 0x35, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x6e, 0x65, 0x76,
+	0x65, 0x72, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x74, 0x65, 0x6e, 0x42, 0x0e, 0x0a, 0x0c, 0x5f, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x6c,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x5f, 0x62, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x73, 0x42, 0x21, 0x5a,
+	0x1f, 0x6b, 0x61, 0x66, 0x6b, 0x61, 0x2d, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x73, 0x2d, 0x72, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x72, 0x6f, 0x77, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_row_proto_rawDescOnce sync.Once
+	file_proto_row_proto_rawDescData = file_proto_row_proto_rawDesc
+)
+
+func file_proto_row_proto_rawDescGZIP() []byte {
+	file_proto_row_proto_rawDescOnce.Do(func() {
+		file_proto_row_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_row_proto_rawDescData)
+	})
+	return file_proto_row_proto_rawDescData
+}
+
+var file_proto_row_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_row_proto_goTypes = []any{
+	(*Row)(nil), // 0: kafka_topics_report.Row
+}
+var file_proto_row_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_row_proto_init() }
+func file_proto_row_proto_init() {
+	if File_proto_row_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proto_row_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Row); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_proto_row_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_row_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_row_proto_goTypes,
+		DependencyIndexes: file_proto_row_proto_depIdxs,
+		MessageInfos:      file_proto_row_proto_msgTypes,
+	}.Build()
+	File_proto_row_proto = out.File
+	file_proto_row_proto_rawDesc = nil
+	file_proto_row_proto_goTypes = nil
+	file_proto_row_proto_depIdxs = nil
+}
@@ -0,0 +1,89 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative proto/row.proto
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	rowpb "kafka-topics-report/proto"
+)
+
+// protobufWriter пишет строки как сообщения Row из proto/row.proto с
+// префиксом длины. Колонки набираются флагами, поэтому поля сообщения
+// заполняются по именам колонок через protoreflect.
+type protobufWriter struct {
+	w      io.Writer
+	header []string
+	fields []protoreflect.FieldDescriptor
+}
+
+func (pw *protobufWriter) Begin() error {
+	desc := (&rowpb.Row{}).ProtoReflect().Descriptor()
+	pw.fields = make([]protoreflect.FieldDescriptor, len(pw.header))
+	for i, name := range pw.header {
+		fd := desc.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("column %s has no field in proto/row.proto", name)
+		}
+		pw.fields[i] = fd
+	}
+	return nil
+}
+
+func (pw *protobufWriter) WriteRow(values []any) error {
+	row := &rowpb.Row{}
+	msg := row.ProtoReflect()
+	for i, v := range values {
+		fd := pw.fields[i]
+		switch v := v.(type) {
+		case nil:
+		case []string:
+			list := msg.Mutable(fd).List()
+			for _, s := range v {
+				list.Append(protoreflect.ValueOfString(s))
+			}
+		case []int32:
+			list := msg.Mutable(fd).List()
+			for _, n := range v {
+				list.Append(protoreflect.ValueOfInt32(n))
+			}
+		default:
+			value, ok := protoScalar(fd, v)
+			if !ok {
+				return fmt.Errorf("column %s: value of type %T does not fit field %s %s", pw.header[i], v, fd.Kind(), fd.Name())
+			}
+			msg.Set(fd, value)
+		}
+	}
+	data, err := proto.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = pw.w.Write(append(binary.AppendUvarint(nil, uint64(len(data))), data...))
+	return err
+}
+
+func (pw *protobufWriter) End() error { return nil }
+
+// protoScalar переводит значение колонки в значение поля fd; ok == false,
+// если тип значения не совпадает с типом поля.
+func protoScalar(fd protoreflect.FieldDescriptor, v any) (value protoreflect.Value, ok bool) {
+	switch v := v.(type) {
+	case string:
+		return protoreflect.ValueOfString(v), fd.Kind() == protoreflect.StringKind
+	case bool:
+		return protoreflect.ValueOfBool(v), fd.Kind() == protoreflect.BoolKind
+	case int32:
+		return protoreflect.ValueOfInt32(v), fd.Kind() == protoreflect.Int32Kind
+	case int64:
+		return protoreflect.ValueOfInt64(v), fd.Kind() == protoreflect.Int64Kind
+	case float64:
+		return protoreflect.ValueOfFloat64(v), fd.Kind() == protoreflect.DoubleKind
+	}
+	return protoreflect.Value{}, false
+}
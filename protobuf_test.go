@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	rowpb "kafka-topics-report/proto"
+)
+
+// protoTestValue — значение колонки того типа, который пишут отчёты для
+// поля fd, и ожидаемое значение после разбора.
+func protoTestValue(t *testing.T, fd protoreflect.FieldDescriptor) (any, any) {
+	switch {
+	case fd.IsList() && fd.Kind() == protoreflect.StringKind:
+		return []string{"svc-a", "with,comma"}, []any{"svc-a", "with,comma"}
+	case fd.IsList() && fd.Kind() == protoreflect.Int32Kind:
+		return []int32{3, -1, 0}, []any{int32(3), int32(-1), int32(0)}
+	case fd.Kind() == protoreflect.StringKind:
+		return "value of " + string(fd.Name()), "value of " + string(fd.Name())
+	case fd.Kind() == protoreflect.Int32Kind:
+		return int32(-7), int32(-7)
+	case fd.Kind() == protoreflect.Int64Kind:
+		return int64(-1 << 40), int64(-1 << 40)
+	case fd.Kind() == protoreflect.BoolKind:
+		return true, true
+	case fd.Kind() == protoreflect.DoubleKind:
+		return 0.25, 0.25
+	}
+	t.Fatalf("field %s: no test value for %s", fd.Name(), fd.Kind())
+	return nil, nil
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	fields := (&rowpb.Row{}).ProtoReflect().Descriptor().Fields()
+
+	header := make([]string, fields.Len())
+	full := make([]any, fields.Len())
+	want := make([]any, fields.Len())
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		header[i] = string(fd.Name())
+		full[i], want[i] = protoTestValue(t, fd)
+	}
+	// вторая строка — только topic, остальные значения отсутствуют
+	sparse := make([]any, len(header))
+	sparse[0] = "sparse"
+
+	var buf bytes.Buffer
+	w := &protobufWriter{w: &buf, header: header}
+	if err := w.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range [][]any{full, sparse} {
+		if err := w.WriteRow(values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	row := &rowpb.Row{}
+	if err := protodelim.UnmarshalFrom(r, row); err != nil {
+		t.Fatal(err)
+	}
+	msg := row.ProtoReflect()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		v := msg.Get(fd)
+		var got any = v.Interface()
+		if fd.IsList() {
+			list := v.List()
+			items := make([]any, list.Len())
+			for j := range list.Len() {
+				items[j] = list.Get(j).Interface()
+			}
+			got = items
+		}
+		if !equalProtoValue(got, want[i]) {
+			t.Errorf("field %s = %v, want %v", fd.Name(), got, want[i])
+		}
+	}
+
+	row = &rowpb.Row{}
+	if err := protodelim.UnmarshalFrom(r, row); err != nil {
+		t.Fatal(err)
+	}
+	row.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Name() != "topic" {
+			t.Errorf("sparse row has field %s = %v", fd.Name(), v)
+		}
+		return true
+	})
+	if got := row.GetTopic(); got != "sparse" {
+		t.Errorf("sparse row topic = %q", got)
+	}
+
+	if err := protodelim.UnmarshalFrom(r, &rowpb.Row{}); !errors.Is(err, io.EOF) {
+		t.Errorf("after two rows: %v, want EOF", err)
+	}
+}
+
+func equalProtoValue(got, want any) bool {
+	g, ok1 := got.([]any)
+	w, ok2 := want.([]any)
+	if ok1 != ok2 {
+		return false
+	}
+	if !ok1 {
+		return got == want
+	}
+	if len(g) != len(w) {
+		return false
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProtobufWriterRejects(t *testing.T) {
+	w := &protobufWriter{w: io.Discard, header: []string{"topic", "no_such_column"}}
+	if err := w.Begin(); err == nil {
+		t.Error("Begin() with a column missing from proto/row.proto succeeded")
+	}
+
+	w = &protobufWriter{w: io.Discard, header: []string{"topic", "messages"}}
+	if err := w.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]any{"orders", "not a number"}); err == nil {
+		t.Error("WriteRow() with a string for an int64 field succeeded")
+	}
+}
@@ -0,0 +1,781 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+type topicStats struct {
+	Partitions int32
+	Messages   int64
+
+	// PartitionMessages — сообщения по партициям (только успешно опрошенные)
+	PartitionMessages map[int32]int64
+
+	// Latest — high watermark каждой партиции на момент SampledAt
+	Latest    map[int32]int64
+	SampledAt time.Time
+
+	// OffsetAtTime — сумма по партициям оффсетов на момент
+	// offsetOptions.AtTimestamp, -1 если его не удалось получить
+	OffsetAtTime int64
+
+	// Incomplete — опрос оффсетов не уложился в --topic-timeout, данные неполные
+	Incomplete bool
+}
+
+// Skew — разница между самой большой и самой маленькой партицией по числу сообщений.
+func (s topicStats) Skew() int64 {
+	first := true
+	var lo, hi int64
+	for _, n := range s.PartitionMessages {
+		if first || n < lo {
+			lo = n
+		}
+		if first || n > hi {
+			hi = n
+		}
+		first = false
+	}
+	return hi - lo
+}
+
+// ActivePartitions — число партиций, в которых есть сообщения.
+func (s topicStats) ActivePartitions() int32 {
+	var n int32
+	for _, m := range s.PartitionMessages {
+		if m > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// OverPartitioned сообщает, что в топике есть сообщения, но в среднем меньше
+// threshold на партицию (--over-partitioned-threshold): такой топик —
+// кандидат на уменьшение числа партиций.
+func (s topicStats) OverPartitioned(threshold float64) bool {
+	if s.Messages <= 0 || s.Partitions == 0 {
+		return false
+	}
+	return float64(s.Messages)/float64(s.Partitions) < threshold
+}
+
+// NeverWritten сообщает, что в топик ни разу не писали: high watermark
+// всех партиций равен 0. Топик, данные которого удалены по retention,
+// сюда не попадает — у него earliest == latest > 0. ok == false, если
+// оффсеты собраны не по всем партициям.
+func (s topicStats) NeverWritten() (never, ok bool) {
+	if s.Incomplete || s.Partitions == 0 || len(s.Latest) < int(s.Partitions) {
+		return false, false
+	}
+	for _, latest := range s.Latest {
+		if latest != 0 {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// skewMetrics — значения --skew-metric: range — разница между самой большой
+// и самой маленькой партицией, stddev — стандартное отклонение числа
+// сообщений по партициям, cv — коэффициент вариации (stddev / среднее).
+var skewMetrics = []string{"range", "stddev", "cv"}
+
+// SkewStddev — стандартное отклонение (по генеральной совокупности) числа
+// сообщений по партициям.
+func (s topicStats) SkewStddev() float64 {
+	mean := s.meanPartitionMessages()
+	var sum float64
+	for _, n := range s.PartitionMessages {
+		d := float64(n) - mean
+		sum += d * d
+	}
+	if len(s.PartitionMessages) == 0 {
+		return 0
+	}
+	return math.Sqrt(sum / float64(len(s.PartitionMessages)))
+}
+
+// P95PartitionMessages — 95-й перцентиль числа сообщений по партициям
+// (метод ближайшего ранга). До 19 партиций включительно совпадает
+// с максимумом.
+func (s topicStats) P95PartitionMessages() int64 {
+	if len(s.PartitionMessages) == 0 {
+		return 0
+	}
+	counts := slices.Sorted(maps.Values(s.PartitionMessages))
+	rank := int(math.Ceil(0.95 * float64(len(counts))))
+	return counts[rank-1]
+}
+
+// SkewCV — коэффициент вариации числа сообщений по партициям, 0 для
+// пустого топика.
+func (s topicStats) SkewCV() float64 {
+	mean := s.meanPartitionMessages()
+	if mean == 0 {
+		return 0
+	}
+	return s.SkewStddev() / mean
+}
+
+func (s topicStats) meanPartitionMessages() float64 {
+	if len(s.PartitionMessages) == 0 {
+		return 0
+	}
+	var total int64
+	for _, n := range s.PartitionMessages {
+		total += n
+	}
+	return float64(total) / float64(len(s.PartitionMessages))
+}
+
+// offsetOptions — настройки подсчёта сообщений по оффсетам.
+type offsetOptions struct {
+	// WindowStart — считать только сообщения, записанные начиная с этого
+	// момента (--message-window); нулевое значение — все сообщения в логе
+	WindowStart time.Time
+
+	// AtTimestamp — момент, для которого считается offset_at_time
+	// (--at-timestamp); нулевое значение — не считать
+	AtTimestamp time.Time
+
+	// Consumer — если задан, сообщения считаются чтением партиций, а не по
+	// разнице оффсетов: так управляющие записи транзакций (commit/abort
+	// маркеры) не попадают в счёт (--exclude-control-records)
+	Consumer sarama.Consumer
+
+	// Errors — счётчик ошибок GetOffset по брокерам-лидерам, nil — не считать
+	Errors *brokerErrors
+
+	// TopicTimeout — предельное время опроса оффсетов одного топика
+	// (--topic-timeout); 0 — без ограничения
+	TopicTimeout time.Duration
+
+	// Batch — оффсеты, заранее полученные пачками по брокерам
+	// (--batch-offsets), nil — опрашивать каждую партицию
+	Batch *offsetBatch
+
+	// Total — считать все сообщения, когда-либо записанные в партицию
+	// (сумма high watermark), а не только хранящиеся сейчас (--count-mode total)
+	Total bool
+}
+
+// countModes — значения --count-mode: retained — сообщения, которые сейчас
+// лежат в логе (latest - earliest), total — все когда-либо записанные
+// (latest). Для топиков с удалением по retention они расходятся.
+var countModes = []string{"retained", "total"}
+
+// brokerErrors считает ошибки запросов оффсетов по брокеру-лидеру партиции,
+// чтобы под -v было видно, не один ли брокер даёт большую часть пропусков.
+// Безопасен для одновременного использования (--topic-timeout).
+type brokerErrors struct {
+	mu     sync.Mutex
+	counts map[int32]int
+}
+
+// record учитывает ошибку по партиции; лидер без метаданных считается как -1.
+func (e *brokerErrors) record(client sarama.Client, t string, p int32) {
+	if e == nil {
+		return
+	}
+	id := int32(-1)
+	if leader, err := client.Leader(t, p); err == nil {
+		id = leader.ID()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.counts == nil {
+		e.counts = make(map[int32]int)
+	}
+	e.counts[id]++
+}
+
+// logSummary выводит число ошибок по брокерам в порядке их номеров.
+func (e *brokerErrors) logSummary() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]int32, 0, len(e.counts))
+	for id := range e.counts {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if id < 0 {
+			log.Printf("unknown leader: %d offset errors", e.counts[id])
+			continue
+		}
+		log.Printf("broker %d: %d offset errors", id, e.counts[id])
+	}
+}
+
+// collectTopicStatsWithin опрашивает оффсеты топика не дольше opts.TopicTimeout.
+// По истечении времени возвращается то, что успели собрать, с Incomplete;
+// если завис сам запрос к брокеру — пустая статистика, и отчёт идёт дальше.
+func collectTopicStatsWithin(client sarama.Client, t string, detail sarama.TopicDetail, opts offsetOptions) topicStats {
+	if opts.TopicTimeout <= 0 {
+		return collectTopicStats(context.Background(), client, t, detail, opts)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.TopicTimeout)
+	defer cancel()
+
+	done := make(chan topicStats, 1)
+	go func() { done <- collectTopicStats(ctx, client, t, detail, opts) }()
+
+	var s topicStats
+	select {
+	case s = <-done:
+	case <-ctx.Done():
+		// sarama не принимает context: зависший GetOffset не прервать,
+		// горутина завершится сама, результат никто не ждёт
+		s = topicStats{Partitions: detail.NumPartitions, OffsetAtTime: -1, Incomplete: true}
+	}
+	if s.Incomplete {
+		log.Printf("WARN: topic=%s: offsets not collected within %s, data is incomplete", t, opts.TopicTimeout)
+	}
+	return s
+}
+
+// collectTopicStats считает число партиций и сообщений в топике. После отмены
+// ctx оставшиеся партиции не опрашиваются, а результат помечается Incomplete.
+func collectTopicStats(ctx context.Context, client sarama.Client, t string, detail sarama.TopicDetail, opts offsetOptions) topicStats {
+	var parts int32 = detail.NumPartitions
+	if parts <= 0 {
+		partitions, err := client.Partitions(t)
+		if err != nil {
+			log.Printf("WARN: failed to get partitions for topic %s: %v", t, err)
+			return topicStats{OffsetAtTime: -1}
+		}
+		parts = int32(len(partitions))
+	}
+
+	var earliestSum, latestSum int64
+	latestByPartition := make(map[int32]int64, parts)
+	messagesByPartition := make(map[int32]int64, parts)
+	sampledAt := time.Now()
+	if opts.Batch != nil {
+		sampledAt = opts.Batch.At
+	}
+	incomplete := false
+	var offsetAtTime int64
+	if opts.AtTimestamp.IsZero() {
+		offsetAtTime = -1
+	}
+
+	for p := int32(0); p < parts; p++ {
+		if ctx.Err() != nil {
+			incomplete = true
+			break
+		}
+		earliest, err := opts.Batch.offset(client, t, p, sarama.OffsetOldest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Oldest) topic=%s partition=%d: %v", t, p, err)
+			opts.Errors.record(client, t, p)
+			continue
+		}
+		latest, err := opts.Batch.offset(client, t, p, sarama.OffsetNewest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Newest) topic=%s partition=%d: %v", t, p, err)
+			opts.Errors.record(client, t, p)
+			continue
+		}
+		earliest, latest, err = checkOffsets(earliest, latest)
+		if err != nil {
+			log.Printf("WARN: topic=%s partition=%d: %v", t, p, err)
+			continue
+		}
+		if !opts.WindowStart.IsZero() {
+			throttle()
+			from, err := client.GetOffset(t, p, opts.WindowStart.UnixMilli())
+			if err != nil {
+				log.Printf("WARN: GetOffset(%s) topic=%s partition=%d: %v", opts.WindowStart.Format(time.RFC3339), t, p, err)
+				opts.Errors.record(client, t, p)
+				continue
+			}
+			// -1: сообщений новее начала окна в партиции нет
+			if from < 0 {
+				from = latest
+			}
+			if from > earliest {
+				earliest = from
+			}
+		}
+		if offsetAtTime >= 0 && !opts.AtTimestamp.IsZero() {
+			throttle()
+			at, err := client.GetOffset(t, p, opts.AtTimestamp.UnixMilli())
+			switch {
+			case err != nil:
+				log.Printf("WARN: GetOffset(%s) topic=%s partition=%d: %v", opts.AtTimestamp.Format(time.RFC3339), t, p, err)
+				opts.Errors.record(client, t, p)
+				offsetAtTime = -1
+			case at < 0:
+				// записей не раньше этого момента нет — вся партиция старше
+				offsetAtTime += latest
+			default:
+				offsetAtTime += at
+			}
+		}
+		if opts.Total {
+			earliest = 0
+		}
+		n := latest - earliest
+		if opts.Consumer != nil && n > 0 {
+			counted, err := countDataRecords(client, opts.Consumer, t, p, earliest, latest)
+			if err != nil {
+				// недосчитанные записи нельзя принять за управляющие:
+				// остаётся разность оффсетов
+				log.Printf("WARN: topic=%s partition=%d: counting records: %v, control records are not excluded", t, p, err)
+			} else {
+				// оффсеты управляющих записей считаем прочитанными
+				earliest += n - counted
+				n = counted
+			}
+		}
+		earliestSum += earliest
+		latestSum += latest
+		latestByPartition[p] = latest
+		messagesByPartition[p] = n
+	}
+
+	messages := latestSum - earliestSum
+	if messages < 0 {
+		messages = latestSum
+	}
+
+	return topicStats{
+		Partitions: parts,
+		Messages:   messages,
+
+		PartitionMessages: messagesByPartition,
+		Latest:            latestByPartition,
+		SampledAt:         sampledAt,
+		OffsetAtTime:      offsetAtTime,
+		Incomplete:        incomplete,
+	}
+}
+
+// controlScanIdleTimeout — сколько ждать следующую запись при подсчёте:
+// управляющие записи consumer не отдаёт, поэтому последний маркер
+// транзакции распознаётся только по тишине.
+const controlScanIdleTimeout = 2 * time.Second
+
+// errCountIncomplete — подсчёт остановлен по тишине до latest, и хвост
+// партиции не подтвердился управляющими записями: брокер мог просто
+// не успеть отдать оставшиеся записи.
+var errCountIncomplete = errors.New("scan stopped before the high watermark")
+
+// countDataRecords читает партицию от earliest до latest и считает записи
+// с данными. Управляющие записи транзакций sarama пропускает сам, оффсеты,
+// удалённые компактизацией, тоже не попадают в счёт. Если записи перестали
+// приходить раньше latest, непрочитанный хвост проверяется запросом Fetch
+// (см. controlTail); неподтверждённый хвост — errCountIncomplete.
+func countDataRecords(client sarama.Client, consumer sarama.Consumer, t string, p int32, earliest, latest int64) (int64, error) {
+	pc, err := consumer.ConsumePartition(t, p, earliest)
+	if err != nil {
+		return 0, err
+	}
+	defer pc.Close()
+
+	var n int64
+	next := earliest
+	idle := time.NewTimer(controlScanIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case msg := <-pc.Messages():
+			if msg.Offset >= latest {
+				return n, nil
+			}
+			n++
+			next = msg.Offset + 1
+			if next >= latest {
+				return n, nil
+			}
+			idle.Reset(controlScanIdleTimeout)
+		case err := <-pc.Errors():
+			return 0, err
+		case <-idle.C:
+			control, err := controlTail(client, t, p, next, latest)
+			if err != nil {
+				return n, fmt.Errorf("%w: read %d records up to offset %d of %d, checking the rest: %v", errCountIncomplete, n, next, latest, err)
+			}
+			if !control {
+				return n, fmt.Errorf("%w: read %d records up to offset %d of %d in %s", errCountIncomplete, n, next, latest, controlScanIdleTimeout)
+			}
+			return n, nil
+		}
+	}
+}
+
+// controlTail сообщает, состоит ли диапазон оффсетов [from, latest)
+// только из управляющих пачек записей. Диапазон читается одним запросом
+// Fetch к лидеру; если ответ не покрыл его целиком или пришёл в старом
+// формате сообщений (до Kafka 0.11 транзакций нет), ответ — false.
+func controlTail(client sarama.Client, t string, p int32, from, latest int64) (bool, error) {
+	cfg := client.Config()
+	if !cfg.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return false, nil
+	}
+	leader, err := client.Leader(t, p)
+	if err != nil {
+		return false, err
+	}
+	req := &sarama.FetchRequest{
+		Version:     4,
+		MaxWaitTime: int32(cfg.Consumer.MaxWaitTime / time.Millisecond),
+		MinBytes:    1,
+		MaxBytes:    sarama.MaxResponseSize,
+		Isolation:   cfg.Consumer.IsolationLevel,
+	}
+	req.AddBlock(t, p, from, cfg.Consumer.Fetch.Default, -1)
+	throttle()
+	resp, err := leader.Fetch(req)
+	if err != nil {
+		return false, err
+	}
+	block := resp.GetBlock(t, p)
+	if block == nil {
+		return false, fmt.Errorf("no fetch response for the partition")
+	}
+	if !errors.Is(block.Err, sarama.ErrNoError) {
+		return false, block.Err
+	}
+	for _, records := range block.RecordsSet {
+		batch := records.RecordBatch
+		if batch == nil {
+			return false, nil
+		}
+		last := batch.FirstOffset + int64(batch.LastOffsetDelta)
+		if last < from {
+			continue
+		}
+		if !batch.Control {
+			return false, nil
+		}
+		from = last + 1
+	}
+	return from >= latest, nil
+}
+
+// partitionHealth по метаданным клиента считает партиции топика, у которых
+// ISR меньше списка реплик, и партиции без лидера.
+func partitionHealth(client sarama.Client, t string, parts int32) (underReplicated, offline int32) {
+	for p := int32(0); p < parts; p++ {
+		if _, err := client.Leader(t, p); err != nil {
+			offline++
+		}
+		replicas, err := client.Replicas(t, p)
+		if err != nil {
+			continue
+		}
+		isr, err := client.InSyncReplicas(t, p)
+		if err != nil {
+			continue
+		}
+		if len(isr) < len(replicas) {
+			underReplicated++
+		}
+	}
+	return underReplicated, offline
+}
+
+// partitionReplicas возвращает реплики и ISR партиции из метаданных клиента
+// в порядке, в котором их отдаёт брокер.
+func partitionReplicas(client sarama.Client, t string, p int32) (replicas, isr []int32) {
+	replicas, err := client.Replicas(t, p)
+	if err != nil {
+		log.Printf("WARN: Replicas topic=%s partition=%d: %v", t, p, err)
+		return nil, nil
+	}
+	isr, err = client.InSyncReplicas(t, p)
+	if err != nil {
+		log.Printf("WARN: InSyncReplicas topic=%s partition=%d: %v", t, p, err)
+		return replicas, nil
+	}
+	return replicas, isr
+}
+
+// partitionLeaderEpoch возвращает эпоху лидера партиции из кешированных
+// метаданных клиента или -1, если она неизвестна: эпоху передают ответы
+// Metadata начиная с Kafka 2.1.0.
+func partitionLeaderEpoch(client sarama.Client, version sarama.KafkaVersion, t string, p int32) int32 {
+	if !version.IsAtLeast(sarama.V2_1_0_0) {
+		return -1
+	}
+	_, epoch, err := client.LeaderAndEpoch(t, p)
+	if err != nil {
+		log.Printf("WARN: LeaderAndEpoch topic=%s partition=%d: %v", t, p, err)
+		return -1
+	}
+	return epoch
+}
+
+// oldestMessageTimeout — сколько ждать первую запись партиции при поиске
+// самого старого сообщения.
+const oldestMessageTimeout = 5 * time.Second
+
+// oldestMessageTime возвращает метку времени самой старой записи топика —
+// минимум по первым записям партиций. ok == false, если записей с меткой
+// времени в топике нет.
+func oldestMessageTime(client sarama.Client, consumer sarama.Consumer, t string, parts int32) (oldest time.Time, ok bool) {
+	for p := int32(0); p < parts; p++ {
+		throttle()
+		earliest, err := client.GetOffset(t, p, sarama.OffsetOldest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Oldest) topic=%s partition=%d: %v", t, p, err)
+			continue
+		}
+		throttle()
+		latest, err := client.GetOffset(t, p, sarama.OffsetNewest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Newest) topic=%s partition=%d: %v", t, p, err)
+			continue
+		}
+		if latest <= earliest {
+			continue
+		}
+		ts, err := firstRecordTime(consumer, t, p, earliest)
+		if err != nil {
+			log.Printf("WARN: topic=%s partition=%d: %v", t, p, err)
+			continue
+		}
+		// у записей старого формата метки времени нет
+		if ts.IsZero() {
+			continue
+		}
+		if !ok || ts.Before(oldest) {
+			oldest, ok = ts, true
+		}
+	}
+	return oldest, ok
+}
+
+func firstRecordTime(consumer sarama.Consumer, t string, p int32, offset int64) (time.Time, error) {
+	pc, err := consumer.ConsumePartition(t, p, offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer pc.Close()
+	select {
+	case msg := <-pc.Messages():
+		return msg.Timestamp, nil
+	case err := <-pc.Errors():
+		return time.Time{}, err
+	case <-time.After(oldestMessageTimeout):
+		return time.Time{}, fmt.Errorf("no record at offset %d within %s", offset, oldestMessageTimeout)
+	}
+}
+
+// replicaLags возвращает наибольшее отставание реплик (OffsetLag из
+// DescribeLogDirs) по партициям топиков из topicSet. Брокеры, которые не
+// ответили, и каталоги с ошибкой пропускаются: по их репликам отставание
+// неизвестно.
+func replicaLags(admin sarama.ClusterAdmin, client sarama.Client, topicSet map[string]bool) map[string]map[int32]int64 {
+	var ids []int32
+	for _, b := range client.Brokers() {
+		ids = append(ids, b.ID())
+	}
+	throttle()
+	dirsByBroker, err := admin.DescribeLogDirs(ids)
+	if err != nil {
+		log.Printf("WARN: DescribeLogDirs: %v", err)
+	}
+
+	lags := make(map[string]map[int32]int64)
+	for id, dirs := range dirsByBroker {
+		for _, dir := range dirs {
+			if !errors.Is(dir.ErrorCode, sarama.ErrNoError) {
+				log.Printf("WARN: DescribeLogDirs(broker=%d, dir=%s): %v", id, dir.Path, dir.ErrorCode)
+				continue
+			}
+			for _, topic := range dir.Topics {
+				if !topicSet[topic.Topic] {
+					continue
+				}
+				if lags[topic.Topic] == nil {
+					lags[topic.Topic] = make(map[int32]int64)
+				}
+				for _, p := range topic.Partitions {
+					if lag, ok := lags[topic.Topic][p.PartitionID]; !ok || p.OffsetLag > lag {
+						lags[topic.Topic][p.PartitionID] = p.OffsetLag
+					}
+				}
+			}
+		}
+	}
+	return lags
+}
+
+// topicReplicationLag — наибольшее отставание реплик среди партиций топика
+// по данным replicaLags, -1 если брокеры не сообщили ни одной партиции.
+func topicReplicationLag(lags map[int32]int64) int64 {
+	if len(lags) == 0 {
+		return -1
+	}
+	return slices.Max(slices.Collect(maps.Values(lags)))
+}
+
+// leaderNotPreferred сообщает, что лидер партиции — не первая (предпочтительная)
+// реплика. ok == false, если лидер или реплики неизвестны.
+func leaderNotPreferred(client sarama.Client, t string, p int32) (notPreferred, ok bool) {
+	leader, err := client.Leader(t, p)
+	if err != nil {
+		return false, false
+	}
+	replicas, err := client.Replicas(t, p)
+	if err != nil || len(replicas) == 0 {
+		return false, false
+	}
+	return leader.ID() != replicas[0], true
+}
+
+// nonPreferredLeaders считает партиции топика, лидер которых — не
+// предпочтительная реплика.
+func nonPreferredLeaders(client sarama.Client, t string, parts int32) int32 {
+	var n int32
+	for p := int32(0); p < parts; p++ {
+		if notPreferred, ok := leaderNotPreferred(client, t, p); ok && notPreferred {
+			n++
+		}
+	}
+	return n
+}
+
+// leaderBrokers — адреса (host:port) брокеров, ведущих партиции топика,
+// без повторов и по возрастанию. Партиции без известного лидера пропускаются.
+func leaderBrokers(client sarama.Client, t string, parts int32) []string {
+	var addrs []string
+	for p := int32(0); p < parts; p++ {
+		leader, err := client.Leader(t, p)
+		if err != nil {
+			continue
+		}
+		if !slices.Contains(addrs, leader.Addr()) {
+			addrs = append(addrs, leader.Addr())
+		}
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+// minReplicas возвращает наименьшее число реплик среди партиций топика.
+// Если метаданные клиента его не дают, берётся replication factor из ListTopics.
+func minReplicas(client sarama.Client, t string, parts int32, detail sarama.TopicDetail) int32 {
+	var lowest int32
+	for p := int32(0); p < parts; p++ {
+		replicas, err := client.Replicas(t, p)
+		if err != nil || len(replicas) == 0 {
+			continue
+		}
+		if n := int32(len(replicas)); lowest == 0 || n < lowest {
+			lowest = n
+		}
+	}
+	if lowest == 0 && detail.ReplicationFactor > 0 {
+		lowest = int32(detail.ReplicationFactor)
+	}
+	return lowest
+}
+
+// topicMinISR возвращает действующее значение min.insync.replicas топика
+// (с учётом значения по умолчанию брокера) или 0, если его не удалось
+// получить.
+func topicMinISR(admin sarama.ClusterAdmin, t string) int32 {
+	throttle()
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        t,
+		ConfigNames: []string{"min.insync.replicas"},
+	})
+	if err != nil {
+		log.Printf("WARN: DescribeConfig(topic=%s): %v", t, err)
+		return 0
+	}
+	for _, e := range entries {
+		if e.Name != "min.insync.replicas" {
+			continue
+		}
+		n, err := strconv.ParseInt(e.Value, 10, 32)
+		if err != nil || n <= 0 {
+			log.Printf("WARN: topic=%s: bad min.insync.replicas %q", t, e.Value)
+			return 0
+		}
+		return int32(n)
+	}
+	return 0
+}
+
+// checkOffsets проверяет пару earliest/latest, полученную от GetOffset.
+// sarama.OffsetNewest (-1) и sarama.OffsetOldest (-2) — константы запроса,
+// а не позиции в логе: если такое значение (или любое другое отрицательное)
+// вернулось в ответе, брокер не определил оффсет, и подставлять вместо него 0
+// нельзя — это исказит число сообщений. Такая партиция пропускается.
+func checkOffsets(earliest, latest int64) (int64, int64, error) {
+	if latest < 0 {
+		return 0, 0, fmt.Errorf("broker returned no high watermark (%s)", offsetName(latest))
+	}
+	if earliest < 0 {
+		return 0, 0, fmt.Errorf("broker returned no log start offset (%s)", offsetName(earliest))
+	}
+	// log start offset мог сдвинуться между двумя запросами
+	if earliest > latest {
+		earliest = latest
+	}
+	return earliest, latest, nil
+}
+
+func offsetName(offset int64) string {
+	switch offset {
+	case sarama.OffsetNewest:
+		return "OffsetNewest"
+	case sarama.OffsetOldest:
+		return "OffsetOldest"
+	default:
+		return strconv.FormatInt(offset, 10)
+	}
+}
+
+// sampleRate повторно снимает high watermark партиций топика и возвращает
+// скорость записи (сообщений в секунду) с момента первого снимка в stats —
+// по топику и по каждой партиции. Учитываются только партиции, по которым
+// удались оба снимка.
+func sampleRate(client sarama.Client, t string, stats topicStats) (float64, map[int32]float64) {
+	now := time.Now()
+	elapsed := now.Sub(stats.SampledAt).Seconds()
+	if elapsed <= 0 {
+		return 0, nil
+	}
+
+	var delta int64
+	partitionRates := make(map[int32]float64, len(stats.Latest))
+	for p, before := range stats.Latest {
+		throttle()
+		latest, err := client.GetOffset(t, p, sarama.OffsetNewest)
+		if err != nil {
+			log.Printf("WARN: GetOffset(Newest) topic=%s partition=%d: %v", t, p, err)
+			continue
+		}
+		// топик могли пересоздать между снимками — отрицательную дельту не считаем
+		var d int64
+		if latest > before {
+			d = latest - before
+		}
+		delta += d
+		partitionRates[p] = roundRate(float64(d) / elapsed)
+	}
+	return roundRate(float64(delta) / elapsed), partitionRates
+}
+
+func roundRate(r float64) float64 {
+	return math.Round(r*100) / 100
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestCheckOffsets(t *testing.T) {
+	tests := []struct {
+		name             string
+		earliest, latest int64
+		wantEarliest     int64
+		wantLatest       int64
+		wantErr          bool
+	}{
+		{"normal", 10, 100, 10, 100, false},
+		{"empty partition", 0, 0, 0, 0, false},
+		{"expired partition", 50, 50, 50, 50, false},
+		{"log start moved past latest", 120, 100, 100, 100, false},
+		{"latest is OffsetNewest", 0, sarama.OffsetNewest, 0, 0, true},
+		{"latest is OffsetOldest", 0, sarama.OffsetOldest, 0, 0, true},
+		{"earliest is OffsetNewest", sarama.OffsetNewest, 100, 0, 0, true},
+		{"earliest is OffsetOldest", sarama.OffsetOldest, 100, 0, 0, true},
+		{"other negative earliest", -7, 100, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earliest, latest, err := checkOffsets(tt.earliest, tt.latest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkOffsets(%d, %d) error = %v, want error %t", tt.earliest, tt.latest, err, tt.wantErr)
+			}
+			if earliest != tt.wantEarliest || latest != tt.wantLatest {
+				t.Errorf("checkOffsets(%d, %d) = %d, %d, want %d, %d", tt.earliest, tt.latest, earliest, latest, tt.wantEarliest, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestControlTail(t *testing.T) {
+	type batch struct {
+		offset  int64
+		control bool
+	}
+	tests := []struct {
+		name         string
+		batches      []batch
+		from, latest int64
+		want         bool
+	}{
+		{"commit marker at the end", []batch{{0, false}, {1, false}, {2, true}}, 2, 3, true},
+		{"several markers", []batch{{0, false}, {1, true}, {2, true}}, 1, 3, true},
+		{"data after marker", []batch{{0, false}, {1, true}, {2, false}}, 1, 3, false},
+		{"response ends before latest", []batch{{0, false}, {1, true}}, 1, 5, false},
+		{"nothing left", []batch{{0, false}}, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			fetch := &sarama.FetchResponse{Version: 4}
+			for _, b := range tt.batches {
+				if b.control {
+					fetch.AddControlRecord("orders", 0, b.offset, 7, sarama.ControlRecordCommit)
+				} else {
+					fetch.AddRecordBatch("orders", 0, nil, sarama.StringEncoder("v"), b.offset, 7, true)
+				}
+			}
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetLeader("orders", 0, broker.BrokerID()),
+				"FetchRequest": sarama.NewMockWrapper(fetch),
+			})
+			cfg := sarama.NewConfig()
+			cfg.Version = sarama.V2_1_0_0
+			client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			got, err := controlTail(client, "orders", 0, tt.from, tt.latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("controlTail(%d, %d) = %t, want %t", tt.from, tt.latest, got, tt.want)
+			}
+		})
+	}
+}
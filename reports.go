@@ -0,0 +1,707 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+var reportModes = []string{"topics", "acls", "shadow", "quotas", "broker-config", "naming", "histogram", "orphan-offsets", "by-group", "assignment", "reassignments", "config-drift"}
+
+// reportHeaders — заголовки отдельных отчётов (кроме topics, у которого
+// колонки зависят от флагов).
+var reportHeaders = map[string][]string{
+	"acls":           aclReportHeader,
+	"shadow":         shadowReportHeader,
+	"quotas":         quotaReportHeader,
+	"broker-config":  brokerConfigReportHeader,
+	"naming":         namingReportHeader,
+	"histogram":      histogramReportHeader,
+	"orphan-offsets": orphanOffsetsReportHeader,
+	"by-group":       byGroupReportHeader,
+	"assignment":     assignmentReportHeader,
+	"reassignments":  reassignmentsReportHeader,
+	"config-drift":   configDriftReportHeader,
+}
+
+var aclReportHeader = []string{"topic", "principal", "operation", "permission", "host"}
+
+// writeACLReport выводит ACL, действующие на топики из списка, включая
+// prefixed-правила и правила на "*".
+func writeACLReport(admin sarama.ClusterAdmin, cfg *sarama.Config, topics []string, out rowWriter) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	// admin.ListAcls теряет код ошибки ответа, а по нему видно, что
+	// авторизатор на кластере не настроен, поэтому запрос отправляем сами
+	controller, err := admin.Controller()
+	if err != nil {
+		return err
+	}
+	req := &sarama.DescribeAclsRequest{
+		AclFilter: sarama.AclFilter{
+			ResourceType:              sarama.AclResourceTopic,
+			ResourcePatternTypeFilter: sarama.AclPatternAny,
+			Operation:                 sarama.AclOperationAny,
+			PermissionType:            sarama.AclPermissionAny,
+		},
+	}
+	if cfg.Version.IsAtLeast(sarama.V2_0_0_0) {
+		req.Version = 1
+	}
+	throttle()
+	resp, err := controller.DescribeAcls(req)
+	if err != nil {
+		return err
+	}
+	switch resp.Err {
+	case sarama.ErrNoError:
+	case sarama.ErrSecurityDisabled:
+		log.Printf("ACLs are not enabled on this cluster (no authorizer configured)")
+		return out.End()
+	default:
+		return resp.Err
+	}
+
+	for _, t := range topics {
+		var rows [][]any
+		for _, res := range resp.ResourceAcls {
+			if !aclMatchesTopic(res.Resource, t) {
+				continue
+			}
+			for _, acl := range res.Acls {
+				rows = append(rows, []any{t, acl.Principal, acl.Operation.String(), acl.PermissionType.String(), acl.Host})
+			}
+		}
+		// полная сортировка, чтобы порядок правил не зависел от ответа брокера
+		sort.Slice(rows, func(i, j int) bool {
+			for k := 1; k < len(rows[i]); k++ {
+				if a, b := rows[i][k].(string), rows[j][k].(string); a != b {
+					return a < b
+				}
+			}
+			return false
+		})
+		for _, row := range rows {
+			if err := out.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return out.End()
+}
+
+func aclMatchesTopic(res sarama.Resource, topic string) bool {
+	switch res.ResourcePatternType {
+	case sarama.AclPatternPrefixed:
+		return strings.HasPrefix(topic, res.ResourceName)
+	default:
+		// до Kafka 2.0 тип шаблона не передаётся, все правила литеральные
+		return res.ResourceName == topic || res.ResourceName == "*"
+	}
+}
+
+var shadowReportHeader = []string{"topic", "status"}
+
+// writeShadowReport сверяет топики с инвентарём: "shadow" — топик есть
+// в кластере (среди отобранных), но не в инвентаре; "missing" — топик
+// из инвентаря отсутствует в кластере.
+func writeShadowReport(topics []string, clusterTopics map[string]sarama.TopicDetail, inventory []string, out rowWriter) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(inventory))
+	for _, name := range inventory {
+		known[name] = true
+	}
+	for _, t := range topics {
+		if known[t] {
+			continue
+		}
+		if err := out.WriteRow([]any{t, "shadow"}); err != nil {
+			return err
+		}
+	}
+
+	var missing []string
+	for name := range known {
+		if _, ok := clusterTopics[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		if err := out.WriteRow([]any{name, "missing"}); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+var quotaReportHeader = []string{"entity_type", "entity_name", "quota_type", "value"}
+
+// writeQuotaReport выводит настроенные квоты клиентов. Для составных
+// сущностей (user + client-id) типы и имена объединяются через "/",
+// квота по умолчанию обозначается как <default>.
+func writeQuotaReport(admin sarama.ClusterAdmin, cfg *sarama.Config, out rowWriter) error {
+	if !cfg.Version.IsAtLeast(sarama.V2_6_0_0) {
+		return fmt.Errorf("client quotas require --kafka-version 2.6.0 or newer")
+	}
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	throttle()
+	entries, err := admin.DescribeClientQuotas(nil, false)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		log.Printf("no client quotas configured")
+	}
+
+	var rows [][]any
+	for _, e := range entries {
+		types := make([]string, len(e.Entity))
+		names := make([]string, len(e.Entity))
+		for i, c := range e.Entity {
+			types[i] = string(c.EntityType)
+			names[i] = c.Name
+			if c.MatchType == sarama.QuotaMatchDefault {
+				names[i] = "<default>"
+			}
+		}
+		for quota, value := range e.Values {
+			rows = append(rows, []any{strings.Join(types, "/"), strings.Join(names, "/"), quota, value})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for k := 0; k < 3; k++ {
+			if a, b := rows[i][k].(string), rows[j][k].(string); a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	for _, row := range rows {
+		if err := out.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+var brokerConfigReportHeader = []string{"broker_id", "config_key", "value", "is_default"}
+
+// writeBrokerConfigReport выводит настройки каждого брокера. keys ограничивает
+// список выводимых параметров, пустой — все параметры. Брокер, который не
+// ответил, пропускается с предупреждением.
+func writeBrokerConfigReport(admin sarama.ClusterAdmin, client sarama.Client, keys []string, out rowWriter) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	brokers := client.Brokers()
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i].ID() < brokers[j].ID() })
+	for _, b := range brokers {
+		throttle()
+		entries, err := admin.DescribeConfig(sarama.ConfigResource{
+			Type:        sarama.BrokerResource,
+			Name:        strconv.Itoa(int(b.ID())),
+			ConfigNames: keys,
+		})
+		if err != nil {
+			log.Printf("WARN: DescribeConfig(broker=%d): %v", b.ID(), err)
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		for _, e := range entries {
+			var value any = e.Value
+			if e.Sensitive {
+				// значение секретных параметров брокер не отдаёт
+				value = nil
+			}
+			if err := out.WriteRow([]any{b.ID(), e.Name, value, e.Default}); err != nil {
+				return err
+			}
+		}
+	}
+	return out.End()
+}
+
+var namingReportHeader = []string{"topic", "compliant"}
+
+// writeNamingReport проверяет имена топиков на соответствие convention
+// и возвращает число нарушений.
+func writeNamingReport(topics []string, convention *regexp.Regexp, out rowWriter) (int, error) {
+	if err := out.Begin(); err != nil {
+		return 0, err
+	}
+	violations := 0
+	for _, t := range topics {
+		compliant := convention.MatchString(t)
+		if !compliant {
+			violations++
+		}
+		if err := out.WriteRow([]any{t, compliant}); err != nil {
+			return violations, err
+		}
+	}
+	return violations, out.End()
+}
+
+var configDriftReportHeader = []string{"topic", "key", "expected", "actual"}
+
+// configBaseline — ожидаемые значения конфигов топиков из --config-baseline:
+// {"*": {"cleanup.policy": "delete"}, "orders": {"retention.ms": "604800000"}}.
+// Ключ "*" задаёт значения для всех топиков, значения для конкретного
+// топика важнее.
+type configBaseline map[string]map[string]string
+
+func readConfigBaseline(path string) (configBaseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b configBaseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("no topics in %s", path)
+	}
+	return b, nil
+}
+
+// expected — ожидаемые значения конфигов топика t.
+func (b configBaseline) expected(t string) map[string]string {
+	merged := maps.Clone(b["*"])
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	maps.Copy(merged, b[t])
+	return merged
+}
+
+// writeConfigDriftReport сравнивает конфиги топиков из DescribeConfig
+// с baseline и выводит расхождения; проверяются только ключи из baseline.
+// Пустое actual — ключа в ответе брокера нет. Возвращает число расхождений.
+func writeConfigDriftReport(admin sarama.ClusterAdmin, topics []string, baseline configBaseline, out rowWriter) (int, error) {
+	if err := out.Begin(); err != nil {
+		return 0, err
+	}
+	drift := 0
+	for _, t := range topics {
+		expected := baseline.expected(t)
+		if len(expected) == 0 {
+			continue
+		}
+		keys := slices.Sorted(maps.Keys(expected))
+		throttle()
+		entries, err := admin.DescribeConfig(sarama.ConfigResource{
+			Type:        sarama.TopicResource,
+			Name:        t,
+			ConfigNames: keys,
+		})
+		if err != nil {
+			log.Printf("WARN: DescribeConfig(topic=%s): %v", t, err)
+			continue
+		}
+		actual := make(map[string]string, len(entries))
+		for _, e := range entries {
+			actual[e.Name] = e.Value
+		}
+		for _, key := range keys {
+			value, ok := actual[key]
+			if ok && value == expected[key] {
+				continue
+			}
+			drift++
+			var cell any
+			if ok {
+				cell = value
+			}
+			if err := out.WriteRow([]any{t, key, expected[key], cell}); err != nil {
+				return drift, err
+			}
+		}
+	}
+	return drift, out.End()
+}
+
+var histogramReportHeader = []string{"bucket", "min_messages", "max_messages", "topics"}
+
+// parseHistogramBuckets разбирает --histogram-buckets: возрастающие верхние
+// границы корзин, например "1000,1000000".
+func parseHistogramBuckets(s string) ([]int64, error) {
+	var bounds []int64
+	for _, item := range splitList(s) {
+		n, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad bound %q, want a positive integer", item)
+		}
+		if len(bounds) > 0 && n <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("bounds must be increasing: %d after %d", n, bounds[len(bounds)-1])
+		}
+		bounds = append(bounds, n)
+	}
+	if len(bounds) == 0 {
+		return nil, fmt.Errorf("at least one bound is required")
+	}
+	return bounds, nil
+}
+
+// writeHistogramReport раскладывает топики по числу сообщений: пустые,
+// затем корзины (предыдущая граница, граница] и всё, что больше последней.
+// Строки партиций не учитываются.
+func writeHistogramReport(rows []Row, bounds []int64, out rowWriter) error {
+	counts := make([]int64, len(bounds)+2)
+	for _, r := range rows {
+		if r.Detail {
+			continue
+		}
+		i := 0
+		if r.Messages > 0 {
+			i = 1 + sort.Search(len(bounds), func(k int) bool { return r.Messages <= bounds[k] })
+		}
+		counts[i]++
+	}
+
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	if err := out.WriteRow([]any{"0", int64(0), int64(0), counts[0]}); err != nil {
+		return err
+	}
+	low := int64(1)
+	for i, high := range bounds {
+		label := humanNumber(float64(low), low) + "-" + humanNumber(float64(high), high)
+		if err := out.WriteRow([]any{label, low, high, counts[i+1]}); err != nil {
+			return err
+		}
+		low = high + 1
+	}
+	last := bounds[len(bounds)-1]
+	if err := out.WriteRow([]any{">" + humanNumber(float64(last), last), low, nil, counts[len(counts)-1]}); err != nil {
+		return err
+	}
+	return out.End()
+}
+
+var orphanOffsetsReportHeader = []string{"group", "topic", "partitions"}
+
+// writeOrphanOffsetsReport выводит пары группа/топик, где у группы есть
+// коммиты по топику, которого нет в кластере. Смотрятся все группы, включая
+// группы без участников: именно такие обычно остаются после удаления топика.
+// Отчёт только показывает оффсеты и ничего не удаляет.
+func writeOrphanOffsetsReport(admin sarama.ClusterAdmin, clusterTopics map[string]sarama.TopicDetail, retry retryPolicy, out rowWriter) error {
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	throttle()
+	groupsMap, err := admin.ListConsumerGroups()
+	if err != nil {
+		return err
+	}
+	groups := make([]string, 0, len(groupsMap))
+	for g := range groupsMap {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		byTopic, err := groupTopicOffsets(admin, g, retry)
+		if err != nil {
+			log.Printf("WARN: ListConsumerGroupOffsets(group=%s): %v", g, err)
+			continue
+		}
+		var orphans []string
+		for topic := range byTopic {
+			if _, ok := clusterTopics[topic]; !ok {
+				orphans = append(orphans, topic)
+			}
+		}
+		sort.Strings(orphans)
+		for _, topic := range orphans {
+			if err := out.WriteRow([]any{g, topic, int32(len(byTopic[topic].Offsets))}); err != nil {
+				return err
+			}
+		}
+	}
+	return out.End()
+}
+
+var byGroupReportHeader = []string{"group", "members", "generation", "topics"}
+
+// groupTopicLag — топик в отчёте by-group.
+type groupTopicLag struct {
+	Topic string `json:"topic"`
+	Lag   int64  `json:"lag"`
+}
+
+// writeByGroupReport переворачивает данные о потреблении: для каждой группы
+// с активными консьюмерами — список читаемых топиков с её отставанием.
+// Колонка topics — вложенный массив, поэтому отчёт выводится только в
+// JSON-форматах. Группы идут по имени, топики — в порядке topics.
+// generation пуст, если поколение группы неизвестно (см. groupGenerations).
+func writeByGroupReport(topics []string, groupsByTopic map[string][]groupConsumption, stats map[string]topicStats, out rowWriter) error {
+	members := make(map[string]int64)
+	generations := make(map[string]any)
+	byGroup := make(map[string][]groupTopicLag)
+	for _, t := range topics {
+		for _, g := range groupsByTopic[t] {
+			members[g.Group] = g.Members
+			generations[g.Group] = nil
+			if g.Generation >= 0 {
+				generations[g.Group] = g.Generation
+			}
+			byGroup[g.Group] = append(byGroup[g.Group], groupTopicLag{t, groupLag(g, stats[t].Latest)})
+		}
+	}
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := out.WriteRow([]any{g, members[g], generations[g], byGroup[g]}); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+// reassignmentPartition — элемент partitions в JSON для
+// kafka-reassign-partitions.sh --reassignment-json-file.
+type reassignmentPartition struct {
+	Topic     string  `json:"topic"`
+	Partition int32   `json:"partition"`
+	Replicas  []int32 `json:"replicas"`
+}
+
+// writeReassignmentTemplate выводит текущее назначение реплик топиков в
+// формате kafka-reassign-partitions.sh, по партиции на строку, чтобы шаблон
+// было удобно править. Партиции с неизвестными репликами пропускаются
+// с предупреждением.
+func writeReassignmentTemplate(w io.Writer, client sarama.Client, topics []string, clusterTopics map[string]sarama.TopicDetail) error {
+	var lines []string
+	for _, t := range topics {
+		for p := int32(0); p < clusterTopics[t].NumPartitions; p++ {
+			replicas, err := client.Replicas(t, p)
+			if err != nil || len(replicas) == 0 {
+				log.Printf("WARN: Replicas topic=%s partition=%d: %v", t, p, err)
+				continue
+			}
+			line, err := json.Marshal(reassignmentPartition{t, p, replicas})
+			if err != nil {
+				return err
+			}
+			lines = append(lines, "    "+string(line))
+		}
+	}
+	body := ""
+	if len(lines) > 0 {
+		body = "\n" + strings.Join(lines, ",\n") + "\n  "
+	}
+	_, err := fmt.Fprintf(w, "{\n  \"version\": 1,\n  \"partitions\": [%s]\n}\n", body)
+	return err
+}
+
+var assignmentReportHeader = []string{"topic", "partition", "member_id", "client_id", "client_host"}
+
+// writeAssignmentReport выводит, какому участнику группы назначена каждая
+// партиция топиков из списка, по данным назначения из DescribeConsumerGroups.
+// Партиции без участника (группа пуста или в ребалансе) не выводятся.
+func writeAssignmentReport(admin sarama.ClusterAdmin, group string, topics []string, out rowWriter) error {
+	throttle()
+	desc, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return err
+	}
+	if len(desc) == 0 {
+		return fmt.Errorf("group %s is not described by the coordinator", group)
+	}
+	if d := desc[0]; !errors.Is(d.Err, sarama.ErrNoError) {
+		return d.Err
+	}
+
+	byPartition := make(map[string]map[int32]*sarama.GroupMemberDescription)
+	for _, m := range desc[0].Members {
+		assign, err := m.GetMemberAssignment()
+		if err != nil {
+			log.Printf("WARN: group %s member %s: bad assignment: %v", group, m.MemberId, err)
+			continue
+		}
+		if assign == nil {
+			continue
+		}
+		for topic, partitions := range assign.Topics {
+			if byPartition[topic] == nil {
+				byPartition[topic] = make(map[int32]*sarama.GroupMemberDescription)
+			}
+			for _, p := range partitions {
+				byPartition[topic][p] = m
+			}
+		}
+	}
+
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	for _, t := range topics {
+		partitions := slices.Sorted(maps.Keys(byPartition[t]))
+		for _, p := range partitions {
+			m := byPartition[t][p]
+			if err := out.WriteRow([]any{t, p, m.MemberId, m.ClientId, strings.TrimPrefix(m.ClientHost, "/")}); err != nil {
+				return err
+			}
+		}
+	}
+	return out.End()
+}
+
+var reassignmentsReportHeader = []string{"topic", "partition", "replicas", "adding_replicas", "removing_replicas", "progress_pct"}
+
+// topicPartition — партиция топика как ключ map.
+type topicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// writeReassignmentsReport выводит идущие переназначения партиций топиков из
+// списка (ListPartitionReassignments, Kafka 2.4+) с грубой оценкой прогресса,
+// см. reassignmentProgress.
+func writeReassignmentsReport(admin sarama.ClusterAdmin, client sarama.Client, cfg *sarama.Config, topics []string, out rowWriter) error {
+	if !cfg.Version.IsAtLeast(sarama.V2_4_0_0) {
+		return fmt.Errorf("ListPartitionReassignments requires --kafka-version 2.4.0 or later")
+	}
+
+	var partitions []topicPartition
+	statuses := make(map[topicPartition]*sarama.PartitionReplicaReassignmentsStatus)
+	adding := make(map[int32]bool)
+	for _, t := range topics {
+		ids, err := client.Partitions(t)
+		if err != nil {
+			log.Printf("WARN: Partitions(topic=%s): %v", t, err)
+			continue
+		}
+		throttle()
+		status, err := admin.ListPartitionReassignments(t, ids)
+		if err != nil {
+			return err
+		}
+		for _, p := range slices.Sorted(maps.Keys(status[t])) {
+			tp := topicPartition{t, p}
+			partitions = append(partitions, tp)
+			statuses[tp] = status[t][p]
+			for _, id := range status[t][p].AddingReplicas {
+				adding[id] = true
+			}
+		}
+	}
+	var lags map[int32]map[topicPartition]int64
+	if len(adding) > 0 {
+		lags = brokerReplicaLags(admin, slices.Sorted(maps.Keys(adding)))
+	}
+
+	if err := out.Begin(); err != nil {
+		return err
+	}
+	for _, tp := range partitions {
+		s := statuses[tp]
+		row := []any{tp.Topic, tp.Partition, brokerList(s.Replicas), brokerList(s.AddingReplicas), brokerList(s.RemovingReplicas), reassignmentProgress(client, tp, s.AddingReplicas, lags)}
+		if err := out.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+// brokerReplicaLags возвращает OffsetLag реплик на каждом из брокеров ids по
+// данным DescribeLogDirs. Брокеры, которые не ответили, в результат не
+// попадают; временные логи (перенос между каталогами брокера) пропускаются.
+func brokerReplicaLags(admin sarama.ClusterAdmin, ids []int32) map[int32]map[topicPartition]int64 {
+	throttle()
+	dirsByBroker, err := admin.DescribeLogDirs(ids)
+	if err != nil {
+		log.Printf("WARN: DescribeLogDirs: %v", err)
+	}
+	lags := make(map[int32]map[topicPartition]int64, len(dirsByBroker))
+	for id, dirs := range dirsByBroker {
+		lags[id] = make(map[topicPartition]int64)
+		for _, dir := range dirs {
+			if !errors.Is(dir.ErrorCode, sarama.ErrNoError) {
+				log.Printf("WARN: DescribeLogDirs(broker=%d, dir=%s): %v", id, dir.Path, dir.ErrorCode)
+				continue
+			}
+			for _, topic := range dir.Topics {
+				for _, p := range topic.Partitions {
+					if !p.IsTemporary {
+						lags[id][topicPartition{topic.Topic, p.PartitionID}] = p.OffsetLag
+					}
+				}
+			}
+		}
+	}
+	return lags
+}
+
+// reassignmentProgress оценивает в процентах, насколько догнала лидера самая
+// отстающая из добавляемых реплик: 100 * (1 - OffsetLag / размер лога лидера
+// в оффсетах). Реплика, которой ещё нет на брокере, даёт 0. Если брокер не
+// ответил или размер лога лидера неизвестен, возвращает nil.
+func reassignmentProgress(client sarama.Client, tp topicPartition, adding []int32, lags map[int32]map[topicPartition]int64) any {
+	if len(adding) == 0 {
+		// остались только удаляемые реплики
+		return 100.0
+	}
+	throttle()
+	newest, err := client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil
+	}
+	throttle()
+	oldest, err := client.GetOffset(tp.Topic, tp.Partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil
+	}
+	size := newest - oldest
+
+	progress := 100.0
+	for _, id := range adding {
+		replicas, known := lags[id]
+		if !known {
+			return nil
+		}
+		lag, ok := replicas[tp]
+		switch {
+		case !ok:
+			return 0.0
+		case size <= 0:
+			// пустой лог: догнать нечего
+		default:
+			pct := 100 * (1 - float64(min(lag, size))/float64(size))
+			progress = min(progress, math.Round(pct*100)/100)
+		}
+	}
+	return progress
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// requestLimiter — общий limiter запросов к кластеру (--rate-limit), nil —
+// без ограничения.
+var requestLimiter *rate.Limiter
+
+// throttle ждёт очереди перед логическим запросом к кластеру: GetOffset,
+// ListOffsets, запросами admin и Describe. Ожидание идёт до отправки, вне
+// таймаутов соединения, и не задерживает рукопожатия TLS и SASL.
+func throttle() {
+	if requestLimiter == nil {
+		return
+	}
+	// Wait без дедлайна отказывает только при burst < 1, а limiter
+	// создаётся с burst 1
+	_ = requestLimiter.Wait(context.Background())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestThrottle(t *testing.T) {
+	start := time.Now()
+	for range 100 {
+		throttle()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("throttle without --rate-limit took %s", elapsed)
+	}
+
+	requestLimiter = rate.NewLimiter(50, 1)
+	defer func() { requestLimiter = nil }()
+	start = time.Now()
+	for range 6 {
+		throttle()
+	}
+	// первый запрос проходит сразу, остальные пять — по одному в 20ms
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 requests at 50/s took %s, want at least 100ms", elapsed)
+	}
+}
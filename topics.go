@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readTopicNames возвращает явно заданный список топиков: "-" — читать
+// имена по одному на строку из stdin, иначе — список через запятую.
+func readTopicNames(spec string, stdin io.Reader) ([]string, error) {
+	if spec != "-" {
+		return splitList(spec), nil
+	}
+	names, err := readNames(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topics from stdin: %w", err)
+	}
+	return names, nil
+}
+
+// countFilter проверяет счётчик по условию из флага (--consumers).
+type countFilter func(n int64) bool
+
+// parseCountFilter разбирает условие вида op:N, где op — eq, ne, gt, ge,
+// lt или le.
+func parseCountFilter(s string) (countFilter, error) {
+	op, value, ok := strings.Cut(s, ":")
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("bad filter %q, want op:N", s)
+	}
+	switch strings.TrimSpace(op) {
+	case "eq":
+		return func(c int64) bool { return c == n }, nil
+	case "ne":
+		return func(c int64) bool { return c != n }, nil
+	case "gt":
+		return func(c int64) bool { return c > n }, nil
+	case "ge":
+		return func(c int64) bool { return c >= n }, nil
+	case "lt":
+		return func(c int64) bool { return c < n }, nil
+	case "le":
+		return func(c int64) bool { return c <= n }, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %q, use one of: eq, ne, gt, ge, lt, le", op)
+	}
+}
+
+// readNamesFile читает имена топиков из файла, по одному на строку.
+func readNamesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readNames(f)
+}
+
+// readTopicPatterns читает glob-шаблоны топиков (синтаксис path.Match) из
+// файла, по одному на строку. Некорректные шаблоны пропускаются
+// с предупреждением.
+func readTopicPatterns(file string) ([]string, error) {
+	lines, err := readNamesFile(file)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]string, 0, len(lines))
+	for _, p := range lines {
+		if _, err := path.Match(p, ""); err != nil {
+			log.Printf("WARN: invalid topic pattern %q: %v", p, err)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// matchAny сообщает, подходит ли имя хотя бы под один шаблон.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalTopic сообщает, начинается ли имя со служебного префикса.
+// Пустой префикс означает, что служебных топиков нет.
+func isInternalTopic(name, prefix string) bool {
+	return prefix != "" && strings.HasPrefix(name, prefix)
+}
+
+// topicCategories — правила классификации топиков для колонки category.
+type topicCategories struct {
+	InternalPrefix      string
+	ChangelogSuffixes   []string
+	RepartitionSuffixes []string
+}
+
+// classify относит топик к internal, changelog, repartition или business.
+// Служебный префикс проверяется первым: топики Kafka Streams в служебном
+// пространстве имён остаются internal.
+func (c topicCategories) classify(name string) string {
+	switch {
+	case isInternalTopic(name, c.InternalPrefix):
+		return "internal"
+	case hasAnySuffix(name, c.ChangelogSuffixes):
+		return "changelog"
+	case hasAnySuffix(name, c.RepartitionSuffixes):
+		return "repartition"
+	default:
+		return "business"
+	}
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// readNames читает имена по одному на строку. Пустые строки и строки,
+// начинающиеся с #, пропускаются (в именах топиков # не допускается).
+func readNames(r io.Reader) ([]string, error) {
+	var names []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name := strings.TrimSpace(sc.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, sc.Err()
+}
+
+// splitList разбивает список через запятую, отбрасывая пустые элементы.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"localhost:9092, ,", []string{"localhost:9092"}},
+		{"a:9092,b:9092", []string{"a:9092", "b:9092"}},
+		{" a:9092 ,\tb:9092 ", []string{"a:9092", "b:9092"}},
+		{"", nil},
+		{" , ,", nil},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsInternalTopic(t *testing.T) {
+	tests := []struct {
+		name, prefix string
+		want         bool
+	}{
+		{"__consumer_offsets", "_", true},
+		{"orders", "_", false},
+		{".internal.audit", ".internal.", true},
+		{"orders", "", false},
+		{"__consumer_offsets", "", false},
+	}
+	for _, tt := range tests {
+		if got := isInternalTopic(tt.name, tt.prefix); got != tt.want {
+			t.Errorf("isInternalTopic(%q, %q) = %t, want %t", tt.name, tt.prefix, got, tt.want)
+		}
+	}
+}